@@ -0,0 +1,338 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+// Package bind provides binding of GPIO lines to the fields of a struct.
+//
+// The lines are described by struct tags on *gpiod.Line fields, e.g.
+//
+//  type Hardware struct {
+//  	Relay *gpiod.Line `gpio:"chip=gpiochip0,line=RELAY1,out,active-low"`
+//  	Door  *gpiod.Line `gpio:"line=DOOR_SENSE,in,pull-up"`
+//  	Led   *gpiod.Line `gpio:"chip=pinctrl-bcm2835,line=17,out=1"`
+//  }
+//
+//  var hw Hardware
+//  b, err := bind.Request(&hw)
+//  if err != nil {
+//  	panic(err)
+//  }
+//  defer b.Close()
+//  hw.Relay.SetValue(1)
+//
+// The tag is a comma separated list of attributes:
+//
+//  chip=<chip>     the name, path or label of the chip containing the line
+//  line=<line>     the name or offset of the line
+//  consumer=<name> the consumer label for the line
+//  as-is           leave the line direction unchanged (default)
+//  in              request the line as an input
+//  out[=<value>]   request the line as an output, with optional initial value
+//  active-low      treat a low line level as active
+//  active-high     treat a high line level as active (default)
+//  push-pull       drive the line both high and low (default for outputs)
+//  open-drain      drive the line low, float high
+//  open-source     drive the line high, float low
+//  pull-up         enable the internal pull-up
+//  pull-down       enable the internal pull-down
+//  bias-disable    disable the internal bias
+//
+// If the chip is not specified then the line must be a line name, and is
+// searched for on all chips.
+//
+// Fields tagged with "-" are ignored.
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/warthog618/gpiod"
+)
+
+// Binding is a set of lines requested for the fields of a struct.
+type Binding struct {
+	lines []*gpiod.Line
+}
+
+// Request requests the lines described by the tagged fields of the struct
+// pointed to by v, and populates the fields with the requested lines.
+//
+// The tags of all fields are validated before any lines are requested.  If
+// any line cannot be requested then any lines already requested are released,
+// and the fields are left unchanged.
+//
+// The options are applied to all lines, before the options from the tags.
+func Request(v interface{}, options ...gpiod.LineOption) (*Binding, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotStructPtr
+	}
+	rv = rv.Elem()
+	ff, err := parseFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	chips := map[string]*gpiod.Chip{}
+	defer func() {
+		for _, c := range chips {
+			c.Close()
+		}
+	}()
+	b := Binding{}
+	for _, f := range ff {
+		l, err := f.request(chips, options)
+		if err != nil {
+			b.Close()
+			return nil, ErrorField{f.name, err}
+		}
+		b.lines = append(b.lines, l)
+	}
+	for i, f := range ff {
+		rv.FieldByIndex(f.index).Set(reflect.ValueOf(b.lines[i]))
+	}
+	return &b, nil
+}
+
+// Close releases all the lines in the binding.
+//
+// Returns the first error encountered, if any.
+func (b *Binding) Close() error {
+	var err error
+	for _, l := range b.lines {
+		if lerr := l.Close(); lerr != nil && err == nil {
+			err = lerr
+		}
+	}
+	return err
+}
+
+// Lines returns the lines requested for the binding, in field order.
+func (b *Binding) Lines() []*gpiod.Line {
+	return b.lines
+}
+
+// field describes a tagged field and the line it is bound to.
+type field struct {
+	name  string
+	index []int
+	chip  string
+	line  string
+	opts  []gpiod.LineOption
+}
+
+var lineType = reflect.TypeOf((*gpiod.Line)(nil))
+
+func parseFields(t reflect.Type) ([]field, error) {
+	ff := []field(nil)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("gpio")
+		if !ok || tag == "-" {
+			continue
+		}
+		if sf.PkgPath != "" {
+			return nil, ErrorField{sf.Name, ErrUnexported}
+		}
+		if sf.Type != lineType {
+			return nil, ErrorField{sf.Name, ErrorBadType{sf.Type.String()}}
+		}
+		f, err := parseTag(tag)
+		if err != nil {
+			return nil, ErrorField{sf.Name, err}
+		}
+		f.name = sf.Name
+		f.index = sf.Index
+		ff = append(ff, f)
+	}
+	return ff, nil
+}
+
+func parseTag(tag string) (field, error) {
+	f := field{}
+	dirn := ""
+	for _, attr := range strings.Split(tag, ",") {
+		attr = strings.TrimSpace(attr)
+		kv := strings.SplitN(attr, "=", 2)
+		key := kv[0]
+		val := ""
+		if len(kv) == 2 {
+			val = kv[1]
+		}
+		switch key {
+		case "chip", "line", "consumer", "out":
+			if key != "out" && len(val) == 0 {
+				return f, ErrorBadAttribute{attr}
+			}
+		default:
+			if len(kv) == 2 {
+				return f, ErrorBadAttribute{attr}
+			}
+		}
+		switch key {
+		case "chip":
+			f.chip = val
+		case "line":
+			f.line = val
+		case "consumer":
+			f.opts = append(f.opts, gpiod.WithConsumer(val))
+		case "as-is":
+			f.opts = append(f.opts, gpiod.AsIs)
+		case "in":
+			f.opts = append(f.opts, gpiod.AsInput)
+		case "out":
+			vv := []int(nil)
+			if len(val) != 0 {
+				v, err := strconv.ParseUint(val, 10, 1)
+				if err != nil {
+					return f, ErrorBadAttribute{attr}
+				}
+				vv = append(vv, int(v))
+			}
+			f.opts = append(f.opts, gpiod.AsOutput(vv...))
+		case "active-low":
+			f.opts = append(f.opts, gpiod.AsActiveLow)
+		case "active-high":
+			f.opts = append(f.opts, gpiod.AsActiveHigh)
+		case "push-pull":
+			f.opts = append(f.opts, gpiod.AsPushPull)
+		case "open-drain":
+			f.opts = append(f.opts, gpiod.AsOpenDrain)
+		case "open-source":
+			f.opts = append(f.opts, gpiod.AsOpenSource)
+		case "pull-up":
+			f.opts = append(f.opts, gpiod.WithPullUp)
+		case "pull-down":
+			f.opts = append(f.opts, gpiod.WithPullDown)
+		case "bias-disable":
+			f.opts = append(f.opts, gpiod.WithBiasDisable)
+		default:
+			return f, ErrorBadAttribute{attr}
+		}
+		switch key {
+		case "as-is", "in", "out":
+			if len(dirn) != 0 {
+				return f, ErrorConflict{dirn, key}
+			}
+			dirn = key
+		}
+	}
+	if len(f.line) == 0 {
+		return f, ErrNoLine
+	}
+	if len(f.chip) == 0 {
+		if _, err := strconv.ParseUint(f.line, 10, 64); err == nil {
+			return f, ErrNoChip
+		}
+	}
+	return f, nil
+}
+
+// request requests the line for the field, opening the chip if necessary.
+func (f *field) request(chips map[string]*gpiod.Chip, options []gpiod.LineOption) (*gpiod.Line, error) {
+	cname := f.chip
+	if len(cname) == 0 {
+		var err error
+		cname, _, err = gpiod.FindLine(f.line)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c, err := openChip(chips, cname)
+	if err != nil {
+		return nil, err
+	}
+	o, err := strconv.ParseUint(f.line, 10, 64)
+	if err != nil {
+		oi, err := c.FindLine(f.line)
+		if err != nil {
+			return nil, err
+		}
+		o = uint64(oi)
+	}
+	opts := append(append([]gpiod.LineOption(nil), options...), f.opts...)
+	return c.RequestLine(int(o), opts...)
+}
+
+// openChip returns the named chip, opening it if it is not already open.
+//
+// The chip may be identified by name, path or label.
+func openChip(chips map[string]*gpiod.Chip, name string) (*gpiod.Chip, error) {
+	if c, ok := chips[name]; ok {
+		return c, nil
+	}
+	c, err := gpiod.NewChip(name)
+	if err != nil {
+		cname, lerr := gpiod.FindChipByLabel(name)
+		if lerr != nil {
+			return nil, err
+		}
+		if c, err = gpiod.NewChip(cname); err != nil {
+			return nil, err
+		}
+	}
+	chips[name] = c
+	return c, nil
+}
+
+var (
+	// ErrNotStructPtr indicates the value to bind is not a pointer to a struct.
+	ErrNotStructPtr = errors.New("not a pointer to a struct")
+
+	// ErrUnexported indicates a tagged field is not exported, so cannot be set.
+	ErrUnexported = errors.New("field is not exported")
+
+	// ErrNoLine indicates the tag does not specify a line.
+	ErrNoLine = errors.New("no line specified")
+
+	// ErrNoChip indicates the tag specifies a line offset but no chip.
+	ErrNoChip = errors.New("no chip specified for line offset")
+)
+
+// ErrorField indicates an error binding a particular field.
+type ErrorField struct {
+	Field string
+	Err   error
+}
+
+func (e ErrorField) Error() string {
+	return fmt.Sprintf("field %s: %s", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrorField) Unwrap() error {
+	return e.Err
+}
+
+// ErrorBadType indicates a tagged field is not a *gpiod.Line.
+type ErrorBadType struct {
+	Type string
+}
+
+func (e ErrorBadType) Error() string {
+	return fmt.Sprintf("unsupported type %s - must be *gpiod.Line", e.Type)
+}
+
+// ErrorBadAttribute indicates an attribute in a tag is unknown or malformed.
+type ErrorBadAttribute struct {
+	Attr string
+}
+
+func (e ErrorBadAttribute) Error() string {
+	return fmt.Sprintf("invalid attribute '%s'", e.Attr)
+}
+
+// ErrorConflict indicates a tag contains conflicting attributes.
+type ErrorConflict struct {
+	Attr1 string
+	Attr2 string
+}
+
+func (e ErrorConflict) Error() string {
+	return fmt.Sprintf("conflicting attributes '%s' and '%s'", e.Attr1, e.Attr2)
+}
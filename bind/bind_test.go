@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package bind_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/bind"
+	"github.com/warthog618/gpiod/mockup"
+	"golang.org/x/sys/unix"
+)
+
+func TestRequestInvalid(t *testing.T) {
+	var l *gpiod.Line
+	patterns := []struct {
+		name string
+		v    interface{}
+		err  error
+	}{
+		{"nil", nil, bind.ErrNotStructPtr},
+		{"not ptr", struct{}{}, bind.ErrNotStructPtr},
+		{"not struct", &l, bind.ErrNotStructPtr},
+		{"unexported",
+			&struct {
+				l *gpiod.Line `gpio:"chip=gpiochip0,line=1"`
+			}{},
+			bind.ErrorField{"l", bind.ErrUnexported}},
+		{"bad type",
+			&struct {
+				L gpiod.Line `gpio:"chip=gpiochip0,line=1"`
+			}{},
+			bind.ErrorField{"L", bind.ErrorBadType{"gpiod.Line"}}},
+		{"no line",
+			&struct {
+				L *gpiod.Line `gpio:"chip=gpiochip0,out"`
+			}{},
+			bind.ErrorField{"L", bind.ErrNoLine}},
+		{"offset without chip",
+			&struct {
+				L *gpiod.Line `gpio:"line=3"`
+			}{},
+			bind.ErrorField{"L", bind.ErrNoChip}},
+		{"unknown attribute",
+			&struct {
+				L *gpiod.Line `gpio:"line=RELAY1,sideways"`
+			}{},
+			bind.ErrorField{"L", bind.ErrorBadAttribute{"sideways"}}},
+		{"empty chip",
+			&struct {
+				L *gpiod.Line `gpio:"chip=,line=RELAY1"`
+			}{},
+			bind.ErrorField{"L", bind.ErrorBadAttribute{"chip="}}},
+		{"unexpected value",
+			&struct {
+				L *gpiod.Line `gpio:"line=RELAY1,in=1"`
+			}{},
+			bind.ErrorField{"L", bind.ErrorBadAttribute{"in=1"}}},
+		{"bad output value",
+			&struct {
+				L *gpiod.Line `gpio:"line=RELAY1,out=2"`
+			}{},
+			bind.ErrorField{"L", bind.ErrorBadAttribute{"out=2"}}},
+		{"conflicting direction",
+			&struct {
+				L *gpiod.Line `gpio:"line=RELAY1,in,out"`
+			}{},
+			bind.ErrorField{"L", bind.ErrorConflict{"in", "out"}}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			b, err := bind.Request(p.v)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, b)
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	m, err := mockup.New([]int{8}, true)
+	require.Nil(t, err)
+	defer m.Close()
+	mc, err := m.Chip(0)
+	require.Nil(t, err)
+
+	hw := struct {
+		Out    *gpiod.Line `gpio:"chip=gpio-mockup-A,line=2,out=1"`
+		In     *gpiod.Line `gpio:"line=gpio-mockup-A-3,in,consumer=bind-test"`
+		Skip   *gpiod.Line `gpio:"-"`
+		Ignore *gpiod.Line
+	}{}
+	b, err := bind.Request(&hw)
+	require.Nil(t, err)
+	require.NotNil(t, b)
+	require.NotNil(t, hw.Out)
+	require.NotNil(t, hw.In)
+	assert.Nil(t, hw.Skip)
+	assert.Nil(t, hw.Ignore)
+	assert.Equal(t, []*gpiod.Line{hw.Out, hw.In}, b.Lines())
+	assert.Equal(t, mc.Name, hw.Out.Chip())
+	assert.Equal(t, 2, hw.Out.Offset())
+	assert.Equal(t, 3, hw.In.Offset())
+	v, err := mc.Value(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	inf, err := hw.In.Info()
+	assert.Nil(t, err)
+	assert.Equal(t, "bind-test", inf.Consumer)
+	assert.False(t, inf.IsOut)
+
+	err = b.Close()
+	assert.Nil(t, err)
+	_, err = hw.Out.Value()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	_, err = hw.In.Value()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestRequestFailure(t *testing.T) {
+	m, err := mockup.New([]int{8}, true)
+	require.Nil(t, err)
+	defer m.Close()
+	mc, err := m.Chip(0)
+	require.Nil(t, err)
+
+	hw := struct {
+		A *gpiod.Line `gpio:"line=gpio-mockup-A-2,in"`
+		B *gpiod.Line `gpio:"line=nonexistent,in"`
+	}{}
+	b, err := bind.Request(&hw)
+	assert.Equal(t, bind.ErrorField{"B", gpiod.ErrLineNotFound}, err)
+	assert.Nil(t, b)
+	assert.Nil(t, hw.A)
+
+	// A was released
+	c, err := gpiod.NewChip(mc.Name)
+	require.Nil(t, err)
+	defer c.Close()
+	inf, err := c.LineInfo(2)
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+}
+
+func TestRequestBusy(t *testing.T) {
+	m, err := mockup.New([]int{8}, true)
+	require.Nil(t, err)
+	defer m.Close()
+	mc, err := m.Chip(0)
+	require.Nil(t, err)
+
+	c, err := gpiod.NewChip(mc.Name)
+	require.Nil(t, err)
+	defer c.Close()
+	busy, err := c.RequestLine(3)
+	require.Nil(t, err)
+	defer busy.Close()
+
+	hw := struct {
+		A *gpiod.Line `gpio:"chip=gpio-mockup-A,line=2,out=1"`
+		B *gpiod.Line `gpio:"chip=gpio-mockup-A,line=3,in"`
+	}{}
+	b, err := bind.Request(&hw)
+	assert.Equal(t, bind.ErrorField{"B", unix.EBUSY}, err)
+	assert.Nil(t, b)
+	assert.Nil(t, hw.A)
+	assert.Nil(t, hw.B)
+
+	// A was released
+	inf, err := c.LineInfo(2)
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+}
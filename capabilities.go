@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// Capabilities is a set of features supported by the kernel and chip.
+type Capabilities uint32
+
+const (
+	// CapBias indicates the bias options are supported.
+	//
+	// Added in Linux v5.5.
+	CapBias Capabilities = 1 << iota
+
+	// CapSetConfig indicates requested lines can be reconfigured.
+	//
+	// Added in Linux v5.5.
+	CapSetConfig
+
+	// CapInfoWatch indicates changes to line info can be watched.
+	//
+	// Added in Linux v5.7.
+	CapInfoWatch
+
+	// CapUapiV2 indicates the kernel provides the v2 GPIO uAPI.
+	//
+	// Added in Linux v5.10.
+	CapUapiV2
+
+	// CapDebounce indicates input lines can be debounced.
+	//
+	// Added in Linux v5.10, and requires the v2 uAPI.
+	CapDebounce
+
+	// CapRealtimeClock indicates edge events can be timestamped using the
+	// realtime clock.
+	//
+	// Added in Linux v5.11, and requires the v2 uAPI.
+	CapRealtimeClock
+)
+
+var capNames = []string{
+	"bias",
+	"set-config",
+	"info-watch",
+	"uapi-v2",
+	"debounce",
+	"realtime-clock",
+}
+
+// Has returns true if all the capabilities in caps are supported.
+func (c Capabilities) Has(caps Capabilities) bool {
+	return c&caps == caps
+}
+
+func (c Capabilities) String() string {
+	names := []string(nil)
+	for i, name := range capNames {
+		if c&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// Capabilities returns the features supported by the running kernel and the
+// chip.
+//
+// The capabilities are probed once, on first use, and cached.
+//
+// The info watch and v2 uAPI capabilities are probed directly from the chip.
+// The remaining capabilities cannot be probed without requesting lines, so
+// are inferred from the probed capabilities and the kernel version.
+func (c *Chip) Capabilities() (Capabilities, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.caps == nil {
+		if c.closed {
+			return 0, ErrClosed
+		}
+		caps := c.probeCapabilities()
+		c.caps = &caps
+	}
+	return *c.caps, nil
+}
+
+// probeCapabilities determines the capabilities of the kernel and chip.
+//
+// Assumes c is locked.
+func (c *Chip) probeCapabilities() Capabilities {
+	if c.sysfs != nil {
+		// sysfs lines can only be reconfigured.
+		return CapSetConfig
+	}
+	var caps Capabilities
+	if c.lines > 0 {
+		fd := c.f.Fd()
+		if c.watches[0] > 0 {
+			// already watched, so probing would disturb the watch.
+			caps |= CapInfoWatch
+		} else {
+			caps |= probeInfoWatch(fd)
+		}
+		if _, err := uapi.GetLineInfoV2(fd, 0); err == nil {
+			caps |= CapUapiV2 | CapDebounce | CapInfoWatch
+		}
+	}
+	if caps.Has(CapInfoWatch) || kernelAtLeast(5, 5) {
+		caps |= CapBias | CapSetConfig
+	}
+	if caps.Has(CapUapiV2) && kernelAtLeast(5, 11) {
+		caps |= CapRealtimeClock
+	}
+	return caps
+}
+
+// probeInfoWatch returns CapInfoWatch if the chip supports watching line info.
+func probeInfoWatch(fd uintptr) Capabilities {
+	li := uapi.LineInfo{Offset: 0}
+	switch uapi.WatchLineInfo(fd, &li) {
+	case nil:
+		uapi.UnwatchLineInfo(fd, 0)
+		return CapInfoWatch
+	case unix.EBUSY:
+		// already watched
+		return CapInfoWatch
+	}
+	return 0
+}
+
+// kernelAtLeast returns true if the running kernel is at least the given
+// version.
+func kernelAtLeast(major, minor int) bool {
+	uname := unix.Utsname{}
+	if err := unix.Uname(&uname); err != nil {
+		return false
+	}
+	var kmajor, kminor int
+	release := uapi.BytesToString(uname.Release[:])
+	if _, err := fmt.Sscanf(release, "%d.%d", &kmajor, &kminor); err != nil {
+		return false
+	}
+	return kmajor > major || (kmajor == major && kminor >= minor)
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warthog618/gpiod"
+)
+
+func TestCapabilitiesString(t *testing.T) {
+	patterns := []struct {
+		name string
+		caps gpiod.Capabilities
+		str  string
+	}{
+		{"none", 0, "none"},
+		{"bias", gpiod.CapBias, "bias"},
+		{"v1", gpiod.CapBias | gpiod.CapSetConfig | gpiod.CapInfoWatch,
+			"bias,set-config,info-watch"},
+		{"v2", gpiod.CapUapiV2 | gpiod.CapDebounce | gpiod.CapRealtimeClock,
+			"uapi-v2,debounce,realtime-clock"},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			assert.Equal(t, p.str, p.caps.String())
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestCapabilitiesHas(t *testing.T) {
+	caps := gpiod.CapBias | gpiod.CapSetConfig
+	assert.True(t, caps.Has(gpiod.CapBias))
+	assert.True(t, caps.Has(gpiod.CapBias|gpiod.CapSetConfig))
+	assert.False(t, caps.Has(gpiod.CapInfoWatch))
+	assert.False(t, caps.Has(gpiod.CapBias|gpiod.CapInfoWatch))
+	assert.True(t, caps.Has(0))
+}
+
+func TestChipCapabilities(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	// an existing watch is not disturbed by the probe
+	_, werr := c.WatchLineInfo(0, func(gpiod.LineInfoChangeEvent) {})
+	caps, err := c.Capabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, werr == nil, caps.Has(gpiod.CapInfoWatch))
+	if werr == nil {
+		err = c.UnwatchLineInfo(0)
+		assert.Nil(t, err)
+		assert.True(t, caps.Has(gpiod.CapBias|gpiod.CapSetConfig))
+	}
+	// the v2 uAPI implies all older features.
+	if caps.Has(gpiod.CapUapiV2) {
+		assert.True(t, caps.Has(gpiod.CapBias|gpiod.CapSetConfig|gpiod.CapInfoWatch))
+	}
+
+	// probe without watch
+	c2 := getChip(t)
+	caps2, err := c2.Capabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, caps, caps2)
+	err = c2.Close()
+	assert.Nil(t, err)
+	// cached
+	caps2, err = c2.Capabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, caps, caps2)
+
+	// closed before probing
+	c2 = getChip(t)
+	c2.Close()
+	_, err = c2.Capabilities()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Clock maps event timestamps to wall clock time.
+//
+// Event timestamps are based on the event clock of the line, which is
+// CLOCK_MONOTONIC by default, though on kernels prior to v5.7 it was
+// CLOCK_REALTIME.  The Clock detects which of those a monotonic timestamp is
+// actually based on, so both are mapped correctly.
+//
+// The mapping from CLOCK_MONOTONIC is calibrated by sampling both clocks, and
+// is recalibrated periodically, to track NTP adjustments to the wall clock,
+// and immediately after the system resumes from suspend, during which
+// CLOCK_MONOTONIC is stopped.
+//
+// Timestamps are mapped using the current calibration, so timestamps from
+// before an NTP step are mapped as if the step had not occurred.  Timestamps
+// from before a suspend are mapped using the last calibration before the
+// suspend, if they precede it.  Timestamps between that calibration and the
+// suspend cannot be distinguished from those after the resume, so are mapped
+// late by the duration of the suspend.
+//
+// A Clock is safe to call from different goroutines.
+type Clock struct {
+	// the maximum age of a calibration before it is recalibrated.
+	maxAge time.Duration
+
+	// mu covers all that follow.
+	mu sync.Mutex
+
+	// CLOCK_REALTIME - CLOCK_MONOTONIC at calibration.
+	offset time.Duration
+
+	// CLOCK_BOOTTIME - CLOCK_MONOTONIC at calibration, which changes when the
+	// system suspends.
+	suspended time.Duration
+
+	// the CLOCK_MONOTONIC time of the calibration.
+	calibrated time.Duration
+
+	// the CLOCK_MONOTONIC time of the last calibration before the most
+	// recent suspend, and the offset at that time.
+	presuspend       time.Duration
+	presuspendOffset time.Duration
+}
+
+// NewClock creates a Clock that recalibrates its mapping at least once per
+// maxAge.
+//
+// If maxAge is not positive then a default of one second is used.
+func NewClock(maxAge time.Duration) *Clock {
+	if maxAge <= 0 {
+		maxAge = time.Second
+	}
+	return &Clock{maxAge: maxAge}
+}
+
+// the clock used by LineEvent.Time and LineInfoChangeEvent.Time.
+var defaultClock = NewClock(0)
+
+// Time returns the wall clock time corresponding to the event timestamp,
+// given the event clock of the line.
+//
+// The timebase of EventClockHTE timestamps depends on the hardware, so they
+// cannot be mapped and the zero Time is returned.
+func (c *Clock) Time(ts time.Duration, clk EventClock) time.Time {
+	switch clk {
+	case EventClockMonotonic:
+	case EventClockRealtime:
+		return time.Unix(0, int64(ts))
+	default:
+		return time.Time{}
+	}
+	now := clockNow(unix.CLOCK_MONOTONIC)
+	c.mu.Lock()
+	switch {
+	case c.calibrated != 0 && c.hasSuspended():
+		c.presuspend = c.calibrated
+		c.presuspendOffset = c.offset
+		c.calibrate()
+	case c.calibrated == 0 || now-c.calibrated > c.maxAge:
+		c.calibrate()
+	}
+	offset := c.offset
+	if ts <= c.presuspend {
+		offset = c.presuspendOffset
+	}
+	c.mu.Unlock()
+	if isRealtime(ts, now, offset) {
+		return time.Unix(0, int64(ts))
+	}
+	return time.Unix(0, int64(ts+offset))
+}
+
+// the change in CLOCK_BOOTTIME - CLOCK_MONOTONIC taken to indicate a suspend,
+// allowing for the time between reading the clocks.
+const suspendThreshold = 10 * time.Millisecond
+
+// hasSuspended returns true if the system has suspended since the last
+// calibration.
+//
+// Assumes c is locked.
+func (c *Clock) hasSuspended() bool {
+	suspended := clockNow(unix.CLOCK_BOOTTIME) - clockNow(unix.CLOCK_MONOTONIC)
+	return suspended-c.suspended > suspendThreshold
+}
+
+// isRealtime returns true if the timestamp is closer to CLOCK_REALTIME than
+// to CLOCK_MONOTONIC.
+func isRealtime(ts, monotonic, offset time.Duration) bool {
+	return ts > monotonic+offset/2
+}
+
+// calibrate determines the offset between the clocks.
+//
+// The offset is taken from the sample with the shortest CLOCK_MONOTONIC
+// interval bracketing the read of CLOCK_REALTIME, so it is least affected by
+// preemption.
+//
+// Assumes c is locked.
+func (c *Clock) calibrate() {
+	best := time.Duration(-1)
+	for i := 0; i < 3; i++ {
+		before := clockNow(unix.CLOCK_MONOTONIC)
+		rt := clockNow(unix.CLOCK_REALTIME)
+		after := clockNow(unix.CLOCK_MONOTONIC)
+		if best < 0 || after-before < best {
+			best = after - before
+			c.offset = rt - (before+after)/2
+			c.calibrated = after
+		}
+	}
+	c.suspended = clockNow(unix.CLOCK_BOOTTIME) - clockNow(unix.CLOCK_MONOTONIC)
+}
+
+// EventClock identifies the clock used to timestamp edge events.
+type EventClock int
+
+const (
+	// EventClockMonotonic indicates events are timestamped using
+	// CLOCK_MONOTONIC.
+	EventClockMonotonic EventClock = iota
+
+	// EventClockRealtime indicates events are timestamped using
+	// CLOCK_REALTIME.
+	//
+	// Requires Linux v5.11 or later.
+	EventClockRealtime
+
+	// EventClockHTE indicates events are timestamped using a hardware
+	// timestamp engine.
+	//
+	// Requires Linux v5.19 or later.
+	EventClockHTE
+)
+
+func (c EventClock) String() string {
+	switch c {
+	case EventClockMonotonic:
+		return "monotonic"
+	case EventClockRealtime:
+		return "realtime"
+	case EventClockHTE:
+		return "hte"
+	}
+	return fmt.Sprintf("unknown(%d)", int(c))
+}
+
+// Time returns the wall clock time at which the event was detected.
+//
+// Lines are requested using the v1 uAPI, which always timestamps events
+// using EventClockMonotonic.
+//
+// Refer to Clock for details of the mapping.
+func (e LineEvent) Time() time.Time {
+	return defaultClock.Time(e.Timestamp, EventClockMonotonic)
+}
+
+// Time returns the wall clock time at which the info change was detected.
+//
+// Info changes are always timestamped using EventClockMonotonic.
+//
+// Refer to Clock for details of the mapping.
+func (e LineInfoChangeEvent) Time() time.Time {
+	return defaultClock.Time(e.Timestamp, EventClockMonotonic)
+}
+
+func clockNow(clock int32) time.Duration {
+	var ts unix.Timespec
+	unix.ClockGettime(clock, &ts)
+	return time.Duration(ts.Nano())
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warthog618/gpiod"
+	"golang.org/x/sys/unix"
+)
+
+func clockNow(clock int32) time.Duration {
+	var ts unix.Timespec
+	unix.ClockGettime(clock, &ts)
+	return time.Duration(ts.Nano())
+}
+
+func TestClock(t *testing.T) {
+	c := gpiod.NewClock(0)
+	patterns := []struct {
+		name  string
+		clock int32
+		eclk  gpiod.EventClock
+	}{
+		{"monotonic", unix.CLOCK_MONOTONIC, gpiod.EventClockMonotonic},
+		// pre-v5.7 kernels reported CLOCK_REALTIME as monotonic
+		{"legacy", unix.CLOCK_REALTIME, gpiod.EventClockMonotonic},
+		{"realtime", unix.CLOCK_REALTIME, gpiod.EventClockRealtime},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			now := time.Now()
+			ts := clockNow(p.clock)
+			assert.WithinDuration(t, now, c.Time(ts, p.eclk), 10*time.Millisecond)
+			// older timestamps map to earlier times
+			assert.WithinDuration(t, now.Add(-time.Second),
+				c.Time(ts-time.Second, p.eclk), 10*time.Millisecond)
+		}
+		t.Run(p.name, tf)
+	}
+	// HTE timestamps cannot be mapped
+	ts := clockNow(unix.CLOCK_MONOTONIC)
+	assert.True(t, c.Time(ts, gpiod.EventClockHTE).IsZero())
+}
+
+func TestClockRecalibrate(t *testing.T) {
+	c := gpiod.NewClock(time.Millisecond)
+	ts := clockNow(unix.CLOCK_MONOTONIC)
+	t1 := c.Time(ts, gpiod.EventClockMonotonic)
+	time.Sleep(5 * time.Millisecond)
+	t2 := c.Time(ts, gpiod.EventClockMonotonic)
+	assert.WithinDuration(t, t1, t2, time.Millisecond)
+}
+
+func TestEventTime(t *testing.T) {
+	now := time.Now()
+	evt := gpiod.LineEvent{Timestamp: clockNow(unix.CLOCK_MONOTONIC)}
+	assert.WithinDuration(t, now, evt.Time(), 10*time.Millisecond)
+	ievt := gpiod.LineInfoChangeEvent{Timestamp: clockNow(unix.CLOCK_MONOTONIC)}
+	assert.WithinDuration(t, now, ievt.Time(), 10*time.Millisecond)
+}
+
+func TestEventClockString(t *testing.T) {
+	assert.Equal(t, "monotonic", gpiod.EventClockMonotonic.String())
+	assert.Equal(t, "realtime", gpiod.EventClockRealtime.String())
+	assert.Equal(t, "hte", gpiod.EventClockHTE.String())
+	assert.Equal(t, "unknown(42)", gpiod.EventClock(42).String())
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// applyClosePolicy applies the close policy to the line.
+//
+// Assumes l is locked.
+func (l *baseLine) applyClosePolicy() error {
+	lo := LineOptions{
+		HandleFlags:   l.flags,
+		InitialValues: l.outputValues,
+		emulate:       l.emulate,
+	}
+	for _, c := range l.closePolicy {
+		c.applyLineConfig(&lo)
+	}
+	if lo.HandleFlags == l.flags {
+		if !l.flags.IsOutput() {
+			return nil
+		}
+		// only the values have changed, so don't require SetLineConfig.
+		var vv uapi.HandleData
+		for i, v := range lo.InitialValues {
+			vv[i] = uint8(v)
+		}
+		if l.sysfs != nil {
+			return l.sysfs.setValues(vv)
+		}
+		return l.writeValues(vv)
+	}
+	return l.reconfigure(lo)
+}
+
+// closePolicies tracks the requested lines that have a close policy, so the
+// policies can be applied if the process is signalled.
+type closePolicies struct {
+	// mu covers all that follow.
+	mu    sync.Mutex
+	lines map[*baseLine]struct{}
+	done  chan struct{}
+}
+
+var policies = closePolicies{lines: map[*baseLine]struct{}{}}
+
+func (p *closePolicies) add(l *baseLine) {
+	p.mu.Lock()
+	p.lines[l] = struct{}{}
+	p.mu.Unlock()
+}
+
+func (p *closePolicies) remove(l *baseLine) {
+	p.mu.Lock()
+	delete(p.lines, l)
+	p.mu.Unlock()
+}
+
+// track adds the line, if it has a close policy.
+//
+// The line must be in the form returned to the caller, not a temporary that
+// is later copied, else a signal would close the temporary rather than the
+// line in use.
+func (p *closePolicies) track(l *baseLine) {
+	if l.closePolicy != nil {
+		p.add(l)
+	}
+}
+
+// closeAll closes all lines with close policies.
+func (p *closePolicies) closeAll() {
+	p.mu.Lock()
+	ll := make([]*baseLine, 0, len(p.lines))
+	for l := range p.lines {
+		ll = append(ll, l)
+	}
+	p.mu.Unlock()
+	for _, l := range ll {
+		l.Close()
+	}
+}
+
+// CloseOnSignal enables the closing of all lines with close policies when the
+// process receives one of the signals, so that the policies are applied
+// before the process exits.
+//
+// If no signals are provided then SIGINT and SIGTERM are used.
+//
+// Once the lines are closed, the handling of the signal is reset to the
+// default and the signal is raised again, so the process terminates as it
+// would have without the handler.  Note that this resets any other handlers
+// registered for the signal via signal.Notify.
+//
+// Repeated calls replace the set of signals.
+func CloseOnSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{unix.SIGINT, unix.SIGTERM}
+	}
+	policies.mu.Lock()
+	defer policies.mu.Unlock()
+	if policies.done != nil {
+		close(policies.done)
+	}
+	done := make(chan struct{})
+	policies.done = done
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, sigs...)
+	go func() {
+		defer signal.Stop(sigch)
+		select {
+		case sig := <-sigch:
+			policies.closeAll()
+			signal.Reset(sig)
+			if s, ok := sig.(syscall.Signal); ok {
+				unix.Kill(os.Getpid(), s)
+			}
+		case <-done:
+		}
+	}()
+}
+
+// StopCloseOnSignal disables the closing of lines on signals enabled by
+// CloseOnSignal.
+func StopCloseOnSignal() {
+	policies.mu.Lock()
+	defer policies.mu.Unlock()
+	if policies.done != nil {
+		close(policies.done)
+		policies.done = nil
+	}
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"golang.org/x/sys/unix"
+)
+
+func TestOnCloseReconfigure(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.FloatingLines()[0]
+
+	wc := make(chan gpiod.LineInfoChangeEvent, 3)
+	_, err := c.WatchLineInfo(offset, func(info gpiod.LineInfoChangeEvent) {
+		wc <- info
+	})
+	require.Nil(t, err)
+	defer c.UnwatchLineInfo(offset)
+
+	l, err := c.RequestLine(offset, gpiod.AsOutput(1),
+		gpiod.OnClose(gpiod.AsInput, gpiod.WithPullDown))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	waitInfoEvent(t, wc, gpiod.LineRequested)
+
+	err = l.Close()
+	assert.Nil(t, err)
+	select {
+	case evt := <-wc:
+		assert.Equal(t, gpiod.LineReconfigured, evt.Type)
+		assert.False(t, evt.Info.IsOut)
+		assert.True(t, evt.Info.PullDown)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+	waitInfoEvent(t, wc, gpiod.LineReleased)
+
+	err = l.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestOnCloseValues(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+
+	c := getChip(t)
+	defer c.Close()
+	lines := platform.FloatingLines()
+
+	wc := make(chan gpiod.LineInfoChangeEvent, 3)
+	_, err := c.WatchLineInfo(lines[0], func(info gpiod.LineInfoChangeEvent) {
+		wc <- info
+	})
+	require.Nil(t, err)
+	defer c.UnwatchLineInfo(lines[0])
+
+	ll, err := c.RequestLines(lines, gpiod.AsOutput(1, 1),
+		gpiod.OnClose(gpiod.AsOutput(0, 0)))
+	require.Nil(t, err)
+	require.NotNil(t, ll)
+	waitInfoEvent(t, wc, gpiod.LineRequested)
+
+	// values only - so no reconfiguration
+	err = ll.Close()
+	assert.Nil(t, err)
+	waitInfoEvent(t, wc, gpiod.LineReleased)
+}
+
+func TestOnCloseInvalid(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	l, err := c.RequestLine(platform.IntrLine(),
+		gpiod.WithBothEdges(func(gpiod.LineEvent) {}),
+		gpiod.OnClose(gpiod.AsInput))
+	assert.Equal(t, gpiod.ErrClosePolicyConflict, err)
+	assert.Nil(t, l)
+
+	l, err = c.RequestLine(platform.FloatingLines()[0],
+		gpiod.AsOutput(1),
+		gpiod.OnClose(gpiod.AsOutput(0, 1)))
+	assert.Equal(t, gpiod.ErrTooManyValues, err)
+	assert.Nil(t, l)
+}
+
+func TestCloseOnSignal(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	l, err := c.RequestLine(platform.FloatingLines()[0], gpiod.AsOutput(1),
+		gpiod.OnClose(gpiod.AsOutput(0)))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+
+	l2, err := c.RequestLine(platform.FloatingLines()[1], gpiod.AsOutput(1))
+	require.Nil(t, err)
+	require.NotNil(t, l2)
+	defer l2.Close()
+
+	// SIGWINCH is ignored by default, so re-raising it is harmless.
+	gpiod.CloseOnSignal(unix.SIGWINCH)
+	defer gpiod.StopCloseOnSignal()
+	p, err := os.FindProcess(os.Getpid())
+	require.Nil(t, err)
+	err = p.Signal(unix.SIGWINCH)
+	require.Nil(t, err)
+
+	closed := false
+	for i := 0; i < 100 && !closed; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, err = l.Value()
+		closed = err == gpiod.ErrClosed
+	}
+	assert.True(t, closed)
+
+	// lines without a close policy are unaffected
+	v, err := l2.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+}
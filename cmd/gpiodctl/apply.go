@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/conf"
+)
+
+func init() {
+	applyCmd.Flags().BoolVarP(&applyOpts.Check, "check", "c", false, "validate the configuration then exit")
+	applyCmd.Flags().BoolVarP(&applyOpts.Exit, "exit", "x", false, "exit immediately after applying the configuration")
+	applyCmd.Flags().BoolVarP(&applyOpts.User, "user", "u", false, "wait for the user to press Enter then exit")
+	applyCmd.Flags().BoolVarP(&applyOpts.Wait, "wait", "w", false, "wait for a SIGINT or SIGTERM to exit")
+	applyCmd.Flags().StringVarP(&applyOpts.Time, "time", "t", "", "wait for a period of time then exit.")
+	applyCmd.SetHelpTemplate(applyCmd.HelpTemplate() + extendedApplyHelp)
+	rootCmd.AddCommand(applyCmd)
+}
+
+var extendedApplyHelp = `
+Configuration:
+  The configuration file is YAML, or JSON or TOML if the file has a .json or
+  .toml extension, and describes the lines, keyed by name, e.g.
+
+    consumer: myapp
+    lines:
+      relay:
+        chip: gpiochip0
+        offset: 4
+        direction: output
+        value: 1
+      door:
+        line: DOOR_SENSE
+        direction: input
+        bias: pull-up
+
+  Refer to the github.com/warthog618/gpiod/conf package for the full format.
+
+Times:
+  A time is a sequence of decimal numbers, each with optional fraction
+  and a mandatory unit suffix, such as "300ms", "1.5h" or "2h45m".
+
+  Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h".
+
+Waiting:
+  If none of --exit, --user, --wait or --time is specified then apply waits
+  for a SIGINT or SIGTERM if any line has edge detection, so the events are
+  reported, else it exits immediately.
+
+Note:
+  On exit the lines revert to their default state.
+`
+
+var (
+	applyCmd = &cobra.Command{
+		Use:   "apply [flags] <config-file>",
+		Short: "Request lines as described by a configuration file",
+		Long: `Request the lines described by a configuration file, report the state of each line, ` +
+			`and maintain the configuration until exit.`,
+		Args:                  cobra.ExactArgs(1),
+		PreRunE:               preapply,
+		RunE:                  apply,
+		DisableFlagsInUseLine: true,
+	}
+	applyOpts = struct {
+		Check bool
+		Exit  bool
+		User  bool
+		Wait  bool
+		Time  string
+	}{}
+)
+
+func preapply(cmd *cobra.Command, args []string) error {
+	return checkWaitTime(applyOpts.Time)
+}
+
+func apply(cmd *cobra.Command, args []string) error {
+	cfg, err := conf.Load(args[0])
+	if err != nil {
+		return err
+	}
+	if len(cfg.Consumer) == 0 {
+		cfg.Consumer = "gpiodctl-apply"
+	}
+	if applyOpts.Check {
+		return cfg.Validate()
+	}
+	d, err := cfg.Request(func(evt gpiod.LineEvent) {
+		edge := "rising"
+		if evt.Type == gpiod.LineEventFallingEdge {
+			edge = "falling"
+		}
+		fmt.Printf("event: %s %-7s %s (%s)\n",
+			evt.Name,
+			edge,
+			evt.Time().Format(time.RFC3339Nano),
+			evt.Timestamp)
+	})
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	for _, name := range d.Names() {
+		v, err := d.Line(name).Value()
+		if err != nil {
+			return fmt.Errorf("error reading GPIO state: %s", err)
+		}
+		fmt.Printf("%s=%d\n", name, v)
+	}
+	if applyOpts.Exit {
+		return nil
+	}
+	sig := applyOpts.Wait
+	if len(applyOpts.Time) == 0 && !applyOpts.Wait && !applyOpts.User {
+		sig = hasEdges(cfg)
+	}
+	if len(applyOpts.Time) != 0 || sig || applyOpts.User {
+		wait(applyOpts.Time, sig, applyOpts.User)
+	}
+	return nil
+}
+
+// hasEdges returns true if any line in the configuration has edge detection.
+func hasEdges(cfg *conf.Config) bool {
+	for _, l := range cfg.Lines {
+		if len(l.Edge) != 0 && l.Edge != "none" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/warthog618/gpiod"
+)
+
+// compatCmds are the libgpiod tools that gpiodctl behaves as when invoked via
+// a link of the same name, e.g. gpioget -> gpiodctl.
+//
+// Each shares the implementation of the equivalent gpiodctl command, but with
+// the flags and output of the libgpiod tool.
+var compatCmds = map[string]*cobra.Command{}
+
+func init() {
+	gpiogetCmd.Flags().BoolVarP(&getOpts.ActiveLow, "active-low", "l", false, "set the line active state to low")
+	gpiogetCmd.Flags().BoolVarP(&getOpts.AsIs, "as-is", "a", false, "request the line as-is rather than as an input")
+	gpiogetCmd.Flags().StringVarP(&getOpts.Bias, "bias", "b", "as-is", "set the line bias")
+	gpiogetCmd.SetHelpTemplate(gpiogetCmd.HelpTemplate() + extendedGetHelp)
+
+	gpiomonCmd.Flags().BoolVarP(&monOpts.ActiveLow, "active-low", "l", false, "set the line active state to low")
+	gpiomonCmd.Flags().UintVarP(&monOpts.NumEvents, "num-events", "n", 0, "exit after processing NUM events")
+	gpiomonCmd.Flags().BoolVarP(&monOpts.Quiet, "silent", "s", false, "don't print event info")
+	gpiomonCmd.Flags().StringVarP(&monOpts.Bias, "bias", "b", "as-is", "set the line bias")
+	gpiomonCmd.Flags().StringVarP(&monOpts.Edge, "edge", "e", "both", "select the edge detection")
+	gpiomonCmd.SetHelpTemplate(gpiomonCmd.HelpTemplate() + extendedMonHelp)
+
+	gpiosetCmd.Flags().BoolVarP(&setOpts.ActiveLow, "active-low", "l", false, "set the line active state to low")
+	gpiosetCmd.Flags().StringVarP(&setOpts.Bias, "bias", "b", "as-is", "set the line bias")
+	gpiosetCmd.Flags().StringVarP(&setOpts.Drive, "drive", "d", "push-pull", "set the line drive")
+	gpiosetCmd.Flags().StringVarP(&gpiosetOpts.Mode, "mode", "m", "exit", "tell the program what to do after setting values")
+	gpiosetCmd.Flags().UintVarP(&gpiosetOpts.Sec, "sec", "s", 0, "specify the number of seconds to wait (only valid for --mode=time)")
+	gpiosetCmd.Flags().UintVarP(&gpiosetOpts.Usec, "usec", "u", 0, "specify the number of microseconds to wait (only valid for --mode=time)")
+	gpiosetCmd.SetHelpTemplate(gpiosetCmd.HelpTemplate() + extendedGpiosetHelp)
+
+	for _, cmd := range []*cobra.Command{
+		gpiodetectCmd,
+		gpiofindCmd,
+		gpiogetCmd,
+		gpioinfoCmd,
+		gpiomonCmd,
+		gpiosetCmd,
+	} {
+		cmd.Flags().BoolP("version", "v", false, "display the version and exit")
+		cmd.Version = version
+		cmd.SetVersionTemplate("{{.Name}} (gpiod) {{.Version}}\n")
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		compatCmds[cmd.Name()] = cmd
+	}
+}
+
+var extendedGpiosetHelp = `
+Biases:
+  as-is:        leave bias unchanged
+  disable:      disable bias
+  pull-up:      enable pull-up
+  pull-down:    enable pull-down
+
+Drives:
+  push-pull:    drive the line both high and low
+  open-drain:   drive the line low or go high impedance
+  open-source:  drive the line high or go high impedance
+
+Modes:
+  exit:         set values and exit immediately
+  wait:         set values and wait for user to press ENTER
+  time:         set values and wait for a specified amount of time
+  signal:       set values and wait for SIGINT or SIGTERM
+
+Note:
+  On exit the line reverts to its default state.
+`
+
+var (
+	gpiodetectCmd = &cobra.Command{
+		Use:                   "gpiodetect [OPTIONS]",
+		Long:                  `List all GPIO chips, print their labels and number of GPIO lines.`,
+		Args:                  cobra.NoArgs,
+		Run:                   detect,
+		DisableFlagsInUseLine: true,
+	}
+	gpiofindCmd = &cobra.Command{
+		Use:                   "gpiofind [OPTIONS] <name>",
+		Long:                  `Find a GPIO line by name. The output of this command can be used as input for gpioget/set.`,
+		Args:                  gpiofindArgs,
+		Run:                   gpiofind,
+		DisableFlagsInUseLine: true,
+	}
+	gpiogetCmd = &cobra.Command{
+		Use:                   "gpioget [OPTIONS] <gpiochip> <offset 1> <offset 2>...",
+		Long:                  `Read line value(s) from a GPIO chip.`,
+		Args:                  requireArgs("gpiochip must be specified", "at least one GPIO line offset must be specified"),
+		RunE:                  get,
+		DisableFlagsInUseLine: true,
+	}
+	gpioinfoCmd = &cobra.Command{
+		Use:                   "gpioinfo [OPTIONS] <gpiochip 1>...",
+		Long:                  `Print information about all lines of the specified GPIO chip(s) (or all gpiochips if none are specified).`,
+		Run:                   info,
+		DisableFlagsInUseLine: true,
+	}
+	gpiomonCmd = &cobra.Command{
+		Use:                   "gpiomon [OPTIONS] <gpiochip> <offset 1> <offset 2>...",
+		Long:                  `Wait for events on GPIO lines and print them to standard output.`,
+		Args:                  requireArgs("gpiochip must be specified", "at least one GPIO line offset must be specified"),
+		RunE:                  mon,
+		DisableFlagsInUseLine: true,
+	}
+	gpiosetCmd = &cobra.Command{
+		Use:                   "gpioset [OPTIONS] <gpiochip> <offset 1>=<value 1> <offset 2>=<value 2>...",
+		Long:                  `Set GPIO line values of a GPIO chip and maintain the state until the process exits.`,
+		Args:                  requireArgs("gpiochip must be specified", "at least one GPIO line offset to value mapping must be specified"),
+		PreRunE:               pregpioset,
+		RunE:                  set,
+		DisableFlagsInUseLine: true,
+	}
+	gpiosetOpts = struct {
+		Mode string
+		Sec  uint
+		Usec uint
+	}{}
+)
+
+// requireArgs returns a validator requiring at least one arg for each of the
+// reasons, with the reason for the first missing arg returned as the error.
+func requireArgs(reasons ...string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) < len(reasons) {
+			return errors.New(reasons[len(args)])
+		}
+		return nil
+	}
+}
+
+func gpiofindArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one GPIO line name must be specified")
+	}
+	return nil
+}
+
+// gpiofind exits silently if the line is not found, and reports any other
+// error, as per libgpiod.
+func gpiofind(cmd *cobra.Command, args []string) {
+	cname, offset, err := gpiod.FindLine(args[0])
+	if err != nil {
+		if err == gpiod.ErrLineNotFound {
+			// the lookup skips chips that cannot be opened, which libgpiod
+			// reports as an error rather than the line not being found.
+			err = openChips()
+		}
+		if err != nil {
+			logErr(cmd, fmt.Errorf("error performing the line lookup: %s", err))
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("%s %d\n", cname, offset)
+}
+
+// openChips returns the first error opening the available chips, if any.
+func openChips() error {
+	for _, name := range gpiod.Chips() {
+		c, err := gpiod.NewChip(name)
+		if err != nil {
+			return err
+		}
+		c.Close()
+	}
+	return nil
+}
+
+// pregpioset maps the gpioset mode onto the equivalent gpiodctl set options.
+func pregpioset(cmd *cobra.Command, args []string) error {
+	if gpiosetOpts.Mode != "time" && (gpiosetOpts.Sec != 0 || gpiosetOpts.Usec != 0) {
+		return errors.New("can't specify wait time in this mode")
+	}
+	switch gpiosetOpts.Mode {
+	case "exit":
+		setOpts.Exit = true
+	case "wait":
+		setOpts.User = true
+	case "time":
+		d := time.Duration(gpiosetOpts.Sec)*time.Second +
+			time.Duration(gpiosetOpts.Usec)*time.Microsecond
+		setOpts.Time = d.String()
+	case "signal":
+		setOpts.Wait = true
+	default:
+		return fmt.Errorf("invalid mode: %s", gpiosetOpts.Mode)
+	}
+	return nil
+}
@@ -8,30 +8,91 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/spf13/cobra"
 	"github.com/warthog618/gpiod"
 )
 
 func init() {
+	findCmd.Flags().BoolVarP(&findOpts.Wildcard, "wildcard", "w", false, "treat the line names as wildcard patterns")
+	findCmd.Flags().BoolVarP(&findOpts.Regex, "regex", "r", false, "treat the line names as regular expressions")
+	findCmd.SetHelpTemplate(findCmd.HelpTemplate() + extendedFindHelp)
 	rootCmd.AddCommand(findCmd)
 }
 
-var findCmd = &cobra.Command{
-	Use:                   "find [flags] <line>...",
-	Short:                 "Find a GPIO line by name",
-	Long:                  `Find a GPIO line by name.  The output of this command can be used as input for gpiod get/set.`,
-	Args:                  cobra.MinimumNArgs(1),
-	Run:                   find,
-	DisableFlagsInUseLine: true,
+var extendedFindHelp = `
+Patterns:
+  Wildcard patterns use shell file name syntax, e.g. "LED*" or "GPIO1?".
+  When matching a pattern all matching lines are reported.
+
+  Without a pattern only the first line with the name is reported, and a
+  warning is logged if the name is used by more than one line.
+`
+
+var (
+	findCmd = &cobra.Command{
+		Use:                   "find [flags] <line>...",
+		Short:                 "Find a GPIO line by name",
+		Long:                  `Find a GPIO line by name.  The output of this command can be used as input for gpiod get/set.`,
+		Args:                  cobra.MinimumNArgs(1),
+		PreRunE:               prefind,
+		Run:                   find,
+		DisableFlagsInUseLine: true,
+	}
+	findOpts = struct {
+		Wildcard bool
+		Regex    bool
+	}{}
+)
+
+func prefind(cmd *cobra.Command, args []string) error {
+	if findOpts.Wildcard && findOpts.Regex {
+		return fmt.Errorf("can't select both wildcard and regex")
+	}
+	return nil
 }
 
 func find(cmd *cobra.Command, args []string) {
+	if findOpts.Wildcard || findOpts.Regex {
+		findMatches(cmd, args)
+		return
+	}
+	dups := gpiod.DuplicateLineNames()
 	for _, linename := range args {
 		if cname, offset, err := gpiod.FindLine(linename); err == nil {
 			fmt.Printf("%s %d\n", cname, offset)
+			if cll, ok := dups[linename]; ok {
+				logErr(cmd, fmt.Errorf("'%s' is used by %d lines", linename, len(cll)))
+			}
 		} else {
 			logErr(cmd, fmt.Errorf("'%s' %s", linename, err))
 		}
 	}
 }
+
+func findMatches(cmd *cobra.Command, args []string) {
+	for _, pattern := range args {
+		var cll []gpiod.ChipLine
+		var err error
+		if findOpts.Regex {
+			var re *regexp.Regexp
+			if re, err = regexp.Compile(pattern); err == nil {
+				cll = gpiod.MatchLinesRegexp(re)
+			}
+		} else {
+			cll, err = gpiod.MatchLines(pattern)
+		}
+		if err != nil {
+			logErr(cmd, fmt.Errorf("'%s' %s", pattern, err))
+			continue
+		}
+		if len(cll) == 0 {
+			logErr(cmd, fmt.Errorf("'%s' %s", pattern, gpiod.ErrLineNotFound))
+			continue
+		}
+		for _, cl := range cll {
+			fmt.Printf("%s %d\n", cl.Chip, cl.Offset)
+		}
+	}
+}
@@ -19,7 +19,7 @@ func init() {
 	getCmd.Flags().BoolVarP(&getOpts.ActiveLow, "active-low", "l", false, "treat the line state as active low")
 	getCmd.Flags().BoolVarP(&getOpts.AsIs, "as-is", "a", false, "request the line as-is rather than as an input")
 	getCmd.Flags().StringVarP(&getOpts.Bias, "bias", "b", "as-is", "set the line bias.")
-	getCmd.SetHelpTemplate(getCmd.HelpTemplate() + extendedGetHelp)
+	getCmd.SetHelpTemplate(getCmd.HelpTemplate() + extendedGetHelp + extendedLinesHelp)
 	rootCmd.AddCommand(getCmd)
 }
 
@@ -33,10 +33,10 @@ Biases:
 
 var (
 	getCmd = &cobra.Command{
-		Use:                   "get [flags] <chip> <offset1>...",
+		Use:                   "get [flags] [chip] <line1>...",
 		Short:                 "Get the state of a line or lines",
-		Long:                  `Read the state of a line or lines from a GPIO chip.`,
-		Args:                  cobra.MinimumNArgs(2),
+		Long:                  `Read the state of a line or lines from one or more GPIO chips.`,
+		Args:                  cobra.MinimumNArgs(1),
 		RunE:                  get,
 		DisableFlagsInUseLine: true,
 	}
@@ -48,23 +48,21 @@ var (
 )
 
 func get(cmd *cobra.Command, args []string) error {
-	name := args[0]
-	oo, err := parseOffsets(args[1:])
+	ls, err := openLines(args)
 	if err != nil {
 		return err
 	}
-	c, err := gpiod.NewChip(name, gpiod.WithConsumer("gpiodctl-get"))
-	if err != nil {
-		return err
+	defer ls.Close()
+	if ls.lines() == 0 {
+		return errNoLines
 	}
-	defer c.Close()
-	opts := makeGetOpts()
-	l, err := c.RequestLines(oo, opts...)
+	opts := append(makeGetOpts(), gpiod.WithConsumer(consumer(cmd)))
+	l, err := ls.request(opts...)
 	if err != nil {
 		return fmt.Errorf("error requesting GPIO line: %s", err)
 	}
 	defer l.Close()
-	vv := make([]int, len(l.Offsets()))
+	vv := make([]int, ls.lines())
 	err = l.Values(vv)
 	if err != nil {
 		return fmt.Errorf("error reading GPIO state: %s", err)
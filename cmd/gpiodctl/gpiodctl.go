@@ -9,6 +9,8 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -23,6 +25,13 @@ var rootCmd = &cobra.Command{
 }
 
 func main() {
+	if cmd, ok := compatCmds[filepath.Base(os.Args[0])]; ok {
+		if err := cmd.Execute(); err != nil {
+			logErr(cmd, err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -30,5 +39,11 @@ func main() {
 }
 
 func logErr(cmd *cobra.Command, err error) {
-	fmt.Fprintf(os.Stderr, "gpiodctl %s: %s\n", cmd.Name(), err)
+	fmt.Fprintf(os.Stderr, "%s: %s\n", cmd.CommandPath(), err)
+}
+
+// consumer returns the consumer label for lines requested by the command,
+// e.g. gpiodctl-get, or gpioget when invoked as gpioget.
+func consumer(cmd *cobra.Command) string {
+	return strings.Replace(cmd.CommandPath(), " ", "-", -1)
 }
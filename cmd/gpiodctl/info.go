@@ -8,25 +8,31 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/warthog618/gpiod"
 )
 
 func init() {
+	infoCmd.Flags().BoolVarP(&infoOpts.JSON, "json", "j", false, "print the info as JSON")
 	rootCmd.AddCommand(infoCmd)
 }
 
-var infoCmd = &cobra.Command{
-	Use:                   "info [flags] [chip]...",
-	Short:                 "Info about chip lines",
-	Long:                  `Print information about all lines of the specified GPIO chip(s) (or all gpiochips if none are specified).`,
-	Run:                   info,
-	DisableFlagsInUseLine: true,
-}
+var (
+	infoCmd = &cobra.Command{
+		Use:                   "info [flags] [chip]...",
+		Short:                 "Info about chip lines",
+		Long:                  `Print information about all lines of the specified GPIO chip(s) (or all gpiochips if none are specified).`,
+		Run:                   info,
+		DisableFlagsInUseLine: true,
+	}
+	infoOpts = struct {
+		JSON bool
+	}{}
+)
 
 func info(cmd *cobra.Command, args []string) {
 	rc := 0
@@ -42,57 +48,28 @@ func info(cmd *cobra.Command, args []string) {
 			rc = 1
 			continue
 		}
-		fmt.Printf("%s - %d lines:\n", c.Name, c.Lines())
-		for o := 0; o < c.Lines(); o++ {
-			li, err := c.LineInfo(o)
+		s, err := c.Snapshot()
+		c.Close()
+		if err != nil {
+			logErr(cmd, err)
+			rc = 1
+			continue
+		}
+		if infoOpts.JSON {
+			b, err := json.Marshal(s)
 			if err != nil {
 				logErr(cmd, err)
 				rc = 1
 				continue
 			}
-			printLineInfo(li)
+			fmt.Println(string(b))
+			continue
 		}
-		c.Close()
+		fmt.Print(s)
 	}
 	os.Exit(rc)
 }
 
 func printLineInfo(li gpiod.LineInfo) {
-	if len(li.Name) == 0 {
-		li.Name = "unnamed"
-	}
-	if li.Requested {
-		if len(li.Consumer) == 0 {
-			li.Consumer = "kernel"
-		}
-		if strings.Contains(li.Consumer, " ") {
-			li.Consumer = "\"" + li.Consumer + "\""
-		}
-	} else {
-		li.Consumer = "unused"
-	}
-	dirn := "input"
-	if li.IsOut {
-		dirn = "output"
-	}
-	active := "active-high"
-	if li.ActiveLow {
-		active = "active-low"
-	}
-	flags := []string(nil)
-	if li.Requested {
-		flags = append(flags, "used")
-	}
-	if li.OpenDrain {
-		flags = append(flags, "open-drain")
-	}
-	if li.OpenSource {
-		flags = append(flags, "open-source")
-	}
-	flstr := ""
-	if len(flags) > 0 {
-		flstr = "[" + strings.Join(flags, " ") + "]"
-	}
-	fmt.Printf("\tline %3d:%12s%12s%8s%13s%s\n",
-		li.Offset, li.Name, li.Consumer, dirn, active, flstr)
+	fmt.Printf("\t%s\n", li)
 }
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/warthog618/gpiod"
+)
+
+var extendedLinesHelp = `
+Lines:
+  Lines may be identified by a chip, by name, path or label, followed by the
+  offsets or names of lines on that chip, e.g. "gpiochip0 4 LED1", or by line
+  names alone, e.g. "LED1 BUTTON", in which case the lines may be spread over
+  multiple chips.
+`
+
+var errNoLines = errors.New("at least one GPIO line must be specified")
+
+// lineSet is a set of lines identified by command line args.
+type lineSet struct {
+	// the chip containing the lines, if identified by the args.
+	c *gpiod.Chip
+
+	// the offsets of the lines on the chip.
+	offsets []int
+
+	// the names of the lines, if the chip is not identified by the args, in
+	// which case the lines may be spread over multiple chips.
+	names []string
+}
+
+// lineRequest is the subset of the methods common to Lines and LineGroup
+// that are required by the commands.
+type lineRequest interface {
+	Values(values []int) error
+	Close() error
+}
+
+// openLines locates the lines identified by args.
+//
+// If the first arg identifies a chip then the remaining args are the offsets
+// or names of lines on that chip, else the args are all line names.
+//
+// If the first arg looks like a chip, or exists but cannot be opened, then
+// the error opening the chip is returned.
+//
+// The lineSet must be closed when no longer required.
+func openLines(args []string) (*lineSet, error) {
+	c, err := openChip(args[0])
+	if err != nil {
+		if isChipID(args[0]) || !os.IsNotExist(err) {
+			return nil, fmt.Errorf("can't open chip '%s': %s", args[0], err)
+		}
+		for i, name := range args {
+			if _, _, err := gpiod.FindLine(name); err != nil {
+				if i == 0 {
+					return nil, fmt.Errorf("can't find chip or line '%s'", name)
+				}
+				return nil, fmt.Errorf("can't find line '%s'", name)
+			}
+		}
+		return &lineSet{names: args}, nil
+	}
+	oo := []int(nil)
+	for _, arg := range args[1:] {
+		o, err := parseLine(c, arg)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		oo = append(oo, o)
+	}
+	return &lineSet{c: c, offsets: oo}, nil
+}
+
+// isChipID returns true if the id looks like the name or path of a chip,
+// rather than a line name or chip label.
+func isChipID(id string) bool {
+	return strings.HasPrefix(id, "/") || strings.HasPrefix(id, "gpiochip")
+}
+
+// openChip opens the chip identified by name, path or label.
+func openChip(id string) (*gpiod.Chip, error) {
+	c, err := gpiod.NewChip(id)
+	if err == nil {
+		return c, nil
+	}
+	if name, lerr := gpiod.FindChipByLabel(id); lerr == nil {
+		return gpiod.NewChip(name)
+	}
+	return nil, err
+}
+
+// parseLine returns the offset of the line on the chip, identified by either
+// offset or name.
+func parseLine(c *gpiod.Chip, arg string) (int, error) {
+	if o, err := strconv.ParseUint(arg, 10, 64); err == nil {
+		if o >= uint64(c.Lines()) {
+			return 0, fmt.Errorf("offset %d is out of range on %s", o, c.Name)
+		}
+		return int(o), nil
+	}
+	o, err := c.FindLine(arg)
+	if err != nil {
+		return 0, fmt.Errorf("can't find line '%s' on %s", arg, c.Name)
+	}
+	return o, nil
+}
+
+// resolveChip opens the chip containing the named lines, and converts the names
+// to offsets on that chip.
+//
+// Returns an error if the lines are spread over multiple chips.
+func (s *lineSet) resolveChip() error {
+	cll, err := gpiod.FindLines(s.names...)
+	if err != nil {
+		return err
+	}
+	oo := make([]int, len(cll))
+	for i, cl := range cll {
+		if cl.Chip != cll[0].Chip {
+			return fmt.Errorf("lines '%s' and '%s' are on different chips",
+				s.names[0], s.names[i])
+		}
+		oo[i] = cl.Offset
+	}
+	c, err := gpiod.NewChip(cll[0].Chip)
+	if err != nil {
+		return err
+	}
+	s.c = c
+	s.offsets = oo
+	s.names = nil
+	return nil
+}
+
+// lines returns the number of lines in the set.
+func (s *lineSet) lines() int {
+	if s.c != nil {
+		return len(s.offsets)
+	}
+	return len(s.names)
+}
+
+// request requests the lines.
+//
+// Values in options, such as AsOutput, are indexed by the position of the
+// line in the args.
+func (s *lineSet) request(options ...gpiod.LineOption) (lineRequest, error) {
+	if s.c != nil {
+		l, err := s.c.RequestLines(s.offsets, options...)
+		if err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	g, err := gpiod.RequestLinesByName(s.names, options...)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// spansChips returns true if the requested lines are spread across more than
+// one chip.
+func spansChips(l lineRequest) bool {
+	g, ok := l.(*gpiod.LineGroup)
+	if !ok {
+		return false
+	}
+	cll := g.Lines()
+	for _, cl := range cll {
+		if cl.Chip != cll[0].Chip {
+			return true
+		}
+	}
+	return false
+}
+
+// Close releases the chip, if any.
+//
+// Lines requested from the set must be closed independently.
+func (s *lineSet) Close() {
+	if s.c != nil {
+		s.c.Close()
+	}
+}
@@ -24,7 +24,7 @@ func init() {
 	monCmd.Flags().StringVarP(&monOpts.Edge, "edge", "e", "both", "select the edge detection.")
 	monCmd.Flags().UintVarP(&monOpts.NumEvents, "num-events", "n", 0, "exit after n edges")
 	monCmd.Flags().BoolVarP(&monOpts.Quiet, "quiet", "q", false, "don't display event details")
-	monCmd.SetHelpTemplate(monCmd.HelpTemplate() + extendedMonHelp)
+	monCmd.SetHelpTemplate(monCmd.HelpTemplate() + extendedMonHelp + extendedLinesHelp)
 	rootCmd.AddCommand(monCmd)
 }
 
@@ -44,10 +44,10 @@ Biases:
 
 var (
 	monCmd = &cobra.Command{
-		Use:                   "mon [flags] <chip> <offset1>...",
+		Use:                   "mon [flags] [chip] <line1>...",
 		Short:                 "Monitor the state of a line or lines",
 		Long:                  `Wait for events on GPIO lines and print them to standard output.`,
-		Args:                  cobra.MinimumNArgs(2),
+		Args:                  cobra.MinimumNArgs(1),
 		RunE:                  mon,
 		DisableFlagsInUseLine: true,
 	}
@@ -61,31 +61,34 @@ var (
 )
 
 func mon(cmd *cobra.Command, args []string) error {
-	name := args[0]
-	oo, err := parseOffsets(args[1:])
+	ls, err := openLines(args)
 	if err != nil {
 		return err
 	}
-	c, err := gpiod.NewChip(name, gpiod.WithConsumer("gpiodctl-mon"))
-	if err != nil {
-		return err
+	defer ls.Close()
+	if ls.lines() == 0 {
+		return errNoLines
 	}
-	defer c.Close()
 	evtchan := make(chan gpiod.LineEvent)
 	eh := func(evt gpiod.LineEvent) {
 		evtchan <- evt
 	}
-	opts := makeMonOpts(eh)
-	l, err := c.RequestLines(oo, opts...)
+	opts := append(makeMonOpts(eh), gpiod.WithConsumer(consumer(cmd)))
+	l, err := ls.request(opts...)
 	if err != nil {
 		return fmt.Errorf("error requesting GPIO lines: %s", err)
 	}
 	defer l.Close()
-	monWait(evtchan)
+	monWait(evtchan, spansChips(l))
 	return nil
 }
 
-func monWait(evtchan <-chan gpiod.LineEvent) {
+// monWait reports the events until the requested number of events have been
+// received or the process is interrupted.
+//
+// The chip is reported for all events if showChip is set, as the offset alone
+// does not identify the line when the lines span multiple chips.
+func monWait(evtchan <-chan gpiod.LineEvent, showChip bool) {
 	sigdone := make(chan os.Signal, 1)
 	signal.Notify(sigdone, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigdone)
@@ -94,16 +97,23 @@ func monWait(evtchan <-chan gpiod.LineEvent) {
 		select {
 		case evt := <-evtchan:
 			if !monOpts.Quiet {
-				t := time.Now()
+				t := evt.Time()
 				edge := "rising"
 				if evt.Type == gpiod.LineEventFallingEdge {
 					edge = "falling"
 				}
-				fmt.Printf("event:%3d %-7s %s (%s)\n",
+				fmt.Printf("event:%3d %-7s %s (%s)",
 					evt.Offset,
 					edge,
 					t.Format(time.RFC3339Nano),
 					evt.Timestamp)
+				if showChip || len(evt.Name) > 0 {
+					fmt.Printf(" %s", evt.Chip)
+				}
+				if len(evt.Name) > 0 {
+					fmt.Printf(" %s", evt.Name)
+				}
+				fmt.Println()
 			}
 			count++
 			if monOpts.NumEvents > 0 && count >= monOpts.NumEvents {
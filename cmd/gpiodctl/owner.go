@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/warthog618/gpiod"
+)
+
+func init() {
+	ownerCmd.SetHelpTemplate(ownerCmd.HelpTemplate() + extendedOwnerHelp)
+	rootCmd.AddCommand(ownerCmd)
+}
+
+var extendedOwnerHelp = `
+The lines held by a request are only reported by the kernel for requests made
+using the v2 uAPI, on Linux v6.7 or later.  Processes holding requests with
+unknown lines are listed as possible owners, with the lines shown as "?".
+When the owner of a line cannot be determined, the possible owners are listed
+after a note saying so.  They are narrowed to the process identified by the
+consumer, if the line was requested with the default consumer, gpiod-<pid>.
+`
+
+var ownerCmd = &cobra.Command{
+	Use:                   "owner [chip] [offset1]...",
+	Short:                 "Identify the processes holding lines",
+	Long:                  `List the processes holding requests for the specified lines, or for any lines if none are specified.`,
+	RunE:                  owner,
+	DisableFlagsInUseLine: true,
+}
+
+func owner(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		oo, err := gpiod.LineOwners()
+		if err != nil {
+			return err
+		}
+		for _, o := range oo {
+			printLineOwner(o)
+		}
+		return nil
+	}
+	c, err := gpiod.NewChip(args[0])
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	oo, err := parseOffsets(args[1:])
+	if err != nil {
+		return err
+	}
+	if len(oo) == 0 {
+		for o := 0; o < c.Lines(); o++ {
+			oo = append(oo, o)
+		}
+	}
+	for _, o := range oo {
+		li, err := c.LineInfo(o)
+		if err != nil {
+			return err
+		}
+		if !li.Requested {
+			continue
+		}
+		owners, err := gpiod.FindLineOwners(c.Name, o)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %d %q:\n", c.Name, o, li.Consumer)
+		if len(owners) == 0 {
+			fmt.Println("\tnot found")
+		} else if owners[0].Offsets == nil {
+			fmt.Println("\towner cannot be determined, possible owners:")
+		}
+		for _, owner := range owners {
+			fmt.Print("\t")
+			printLineOwner(owner)
+		}
+	}
+	return nil
+}
+
+func printLineOwner(o gpiod.LineOwner) {
+	chip := o.Chip
+	lines := "?"
+	if len(chip) == 0 {
+		chip = "?"
+	}
+	if o.Offsets != nil {
+		ll := make([]string, len(o.Offsets))
+		for i, lo := range o.Offsets {
+			ll[i] = strconv.Itoa(lo)
+		}
+		lines = strings.Join(ll, ",")
+	}
+	fmt.Printf("%s %s pid=%d fd=%d %s\n",
+		chip, lines, o.Pid, o.Fd, strings.Join(o.Cmdline, " "))
+}
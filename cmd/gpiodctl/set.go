@@ -27,7 +27,7 @@ func init() {
 	setCmd.Flags().BoolVarP(&setOpts.User, "user", "u", false, "wait for the user to press Enter then exit")
 	setCmd.Flags().BoolVarP(&setOpts.Wait, "wait", "w", false, "wait for a SIGINT or SIGTERM to exit")
 	setCmd.Flags().StringVarP(&setOpts.Time, "time", "t", "", "wait for a period of time then exit.")
-	setCmd.SetHelpTemplate(setCmd.HelpTemplate() + extendedSetHelp)
+	setCmd.SetHelpTemplate(setCmd.HelpTemplate() + extendedSetHelp + extendedLinesHelp)
 	rootCmd.AddCommand(setCmd)
 }
 
@@ -55,10 +55,10 @@ Note:
 
 var (
 	setCmd = &cobra.Command{
-		Use:                   "set [flags] <chip> <offset1>=<state1>...",
+		Use:                   "set [flags] [chip] <line1>=<state1>...",
 		Short:                 "Set the state of a line or lines",
-		Long:                  `Set the state of lines on a GPIO chip and maintain the state until exit.`,
-		Args:                  cobra.MinimumNArgs(2),
+		Long:                  `Set the state of lines on one or more GPIO chips and maintain the state until exit.`,
+		Args:                  cobra.MinimumNArgs(1),
 		PreRunE:               preset,
 		RunE:                  set,
 		DisableFlagsInUseLine: true,
@@ -70,60 +70,82 @@ var (
 		Wait      bool
 		User      bool
 		Time      string
+
+		// set by gpioset --mode=exit to exit immediately.
+		Exit bool
 	}{}
 )
 
 func preset(cmd *cobra.Command, args []string) error {
-	if len(setOpts.Time) != 0 {
-		d, err := time.ParseDuration(setOpts.Time)
+	return checkWaitTime(setOpts.Time)
+}
+
+// checkWaitTime checks that the time to wait, if any, is a valid duration.
+func checkWaitTime(period string) error {
+	if len(period) != 0 {
+		d, err := time.ParseDuration(period)
 		if err != nil {
 			return err
 		}
 		if d < 0 {
-			return fmt.Errorf("time (%s) must be positive", setOpts.Time)
+			return fmt.Errorf("time (%s) must be positive", period)
 		}
 	}
 	return nil
 }
 
 func set(cmd *cobra.Command, args []string) error {
-	name := args[0]
-	ll := []int(nil)
+	ids := []string(nil)
 	vv := []int(nil)
-	for _, arg := range args[1:] {
-		o, v, err := parseLineValue(arg)
+	for i, arg := range args {
+		if i == 0 && !strings.Contains(arg, "=") {
+			// chip
+			ids = append(ids, arg)
+			continue
+		}
+		id, v, err := parseLineValue(arg)
 		if err != nil {
 			return err
 		}
-		ll = append(ll, o)
+		ids = append(ids, id)
 		vv = append(vv, v)
 	}
-	c, err := gpiod.NewChip(name, gpiod.WithConsumer("gpiodctl-set"))
+	ls, err := openLines(ids)
 	if err != nil {
 		return err
 	}
-	defer c.Close()
-	opts := makeSetOpts(vv)
-	l, err := c.RequestLines(ll, opts...)
+	defer ls.Close()
+	if ls.c == nil && len(ids) > len(vv) {
+		return fmt.Errorf("can't find chip '%s'", ids[0])
+	}
+	if ls.lines() == 0 {
+		return errNoLines
+	}
+	opts := append(makeSetOpts(vv), gpiod.WithConsumer(consumer(cmd)))
+	l, err := ls.request(opts...)
 	if err != nil {
 		return fmt.Errorf("error requesting GPIO line: %s", err)
 	}
 	defer l.Close()
-	setWait()
+	if !setOpts.Exit {
+		wait(setOpts.Time, setOpts.Wait, setOpts.User)
+	}
 	return nil
 }
 
-func setWait() {
+// wait blocks until the period has elapsed, or the process is signalled if
+// sig, or the user presses Enter if user, whichever occurs first.
+func wait(period string, sig, user bool) {
 	done := make(chan int)
-	if len(setOpts.Time) > 0 {
-		duration, _ := time.ParseDuration(setOpts.Time)
+	if len(period) > 0 {
+		duration, _ := time.ParseDuration(period)
 		fmt.Printf("waiting for %s...\n", duration)
 		go func() {
 			time.Sleep(duration)
 			done <- 1
 		}()
 	}
-	if setOpts.Wait {
+	if sig {
 		sigdone := make(chan os.Signal, 1)
 		signal.Notify(sigdone, os.Interrupt, syscall.SIGTERM)
 		defer signal.Stop(sigdone)
@@ -133,7 +155,7 @@ func setWait() {
 			done <- 2
 		}()
 	}
-	if setOpts.User {
+	if user {
 		fmt.Println("Press enter to exit...")
 		go func() {
 			reader := bufio.NewReader(os.Stdin)
@@ -174,18 +196,17 @@ func makeSetOpts(vv []int) []gpiod.LineOption {
 	return opts
 }
 
-func parseLineValue(arg string) (int, int, error) {
+func parseLineValue(arg string) (string, int, error) {
 	aa := strings.Split(arg, "=")
 	if len(aa) != 2 {
-		return 0, 0, fmt.Errorf("invalid offset<->state mapping: %s", arg)
+		return "", 0, fmt.Errorf("invalid line<->state mapping: %s", arg)
 	}
-	o, err := strconv.ParseUint(aa[0], 10, 64)
-	if err != nil {
-		return 0, 0, fmt.Errorf("can't parse offset '%s'", arg)
+	if len(aa[0]) == 0 {
+		return "", 0, fmt.Errorf("can't parse line '%s'", arg)
 	}
 	v, err := strconv.ParseInt(aa[1], 10, 64)
 	if err != nil {
-		return 0, 0, fmt.Errorf("can't parse state '%s'", arg)
+		return "", 0, fmt.Errorf("can't parse state '%s'", arg)
 	}
-	return int(o), int(v), nil
+	return aa[0], int(v), nil
 }
@@ -20,14 +20,15 @@ import (
 func init() {
 	watchCmd.Flags().UintVarP(&watchOpts.NumEvents, "num-events", "n", 0, "exit after n events")
 	watchCmd.Flags().BoolVarP(&watchOpts.Verbose, "verbose", "v", false, "display complete line info")
+	watchCmd.SetHelpTemplate(watchCmd.HelpTemplate() + extendedLinesHelp)
 	rootCmd.AddCommand(watchCmd)
 }
 
 var (
 	watchCmd = &cobra.Command{
-		Use:                   "watch [flags] <chip> [offset1]...",
+		Use:                   "watch [flags] [chip] [line1]...",
 		Short:                 "Watch lines for changes to the line info",
-		Long:                  `Wait for changes to info on GPIO lines and print them to standard output.`,
+		Long:                  `Wait for changes to info on GPIO lines and print them to standard output.  If only a chip is specified then all lines on the chip are watched.  Lines identified by name must all be on the same chip.`,
 		Args:                  cobra.MinimumNArgs(1),
 		RunE:                  watch,
 		DisableFlagsInUseLine: true,
@@ -39,38 +40,30 @@ var (
 )
 
 func watch(cmd *cobra.Command, args []string) error {
-	name := args[0]
-	oo, err := parseOffsets(args[1:])
+	ls, err := openLines(args)
 	if err != nil {
 		return err
 	}
-	c, err := gpiod.NewChip(name)
-	if err != nil {
-		return err
+	defer ls.Close()
+	if ls.c == nil {
+		// info events only identify lines by offset, so restrict to one chip.
+		if err := ls.resolveChip(); err != nil {
+			return err
+		}
 	}
-	defer c.Close()
+	c, oo := ls.c, ls.offsets
 	evtchan := make(chan gpiod.LineInfoChangeEvent)
 	eh := func(evt gpiod.LineInfoChangeEvent) {
 		evtchan <- evt
 	}
-	for _, o := range oo {
-		info, err := c.WatchLineInfo(o, eh)
-		if err != nil {
-			return fmt.Errorf("error requesting watch on line %d: %s", o, err)
-		}
-		if watchOpts.Verbose {
-			printLineInfo(info)
-		}
+	w, info, err := c.SubscribeLineInfo(eh, oo...)
+	if err != nil {
+		return fmt.Errorf("error requesting watch: %s", err)
 	}
-	if len(oo) == 0 {
-		for o := 0; o < c.Lines(); o++ {
-			info, err := c.WatchLineInfo(o, eh)
-			if err != nil {
-				return fmt.Errorf("error requesting watch on line %d: %s", o, err)
-			}
-			if watchOpts.Verbose {
-				printLineInfo(info)
-			}
+	defer w.Close()
+	if watchOpts.Verbose {
+		for _, li := range info {
+			printLineInfo(li)
 		}
 	}
 	watchWait(evtchan)
@@ -90,7 +83,7 @@ func watchWait(evtchan <-chan gpiod.LineInfoChangeEvent) {
 	for {
 		select {
 		case evt := <-evtchan:
-			t := time.Now()
+			t := evt.Time()
 			fmt.Printf("event:%3d %-12s %s (%s)\n",
 				evt.Info.Offset,
 				etypes[evt.Type],
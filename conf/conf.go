@@ -0,0 +1,406 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+// Package conf provides declarative configuration of GPIO lines.
+//
+// The configuration describes the lines used by a device, and may be loaded
+// from a YAML, JSON or TOML file, e.g.
+//
+//  consumer: myapp
+//  chips:
+//    gpiochip0:
+//      bias: pull-up
+//  lines:
+//    relay:
+//      chip: gpiochip0
+//      offset: 4
+//      direction: output
+//      value: 1
+//      drive: open-drain
+//    door:
+//      line: DOOR_SENSE
+//      direction: input
+//      active-low: true
+//      edge: both
+//
+// Lines are keyed by a logical name, and located either by chip and offset,
+// or by line name with an optional chip.  Chips may be identified by name,
+// path or label.
+//
+// Chip attributes:
+//
+//  active-low  true or false
+//  bias        as-is, disable, pull-up or pull-down
+//
+// Line attributes:
+//
+//  chip        the name, path or label of the chip containing the line
+//  line        the name of the line
+//  offset      the offset of the line on the chip
+//  consumer    the consumer label for the line
+//  direction   as-is, input or output
+//  value       the initial value of an output
+//  active-low  true or false
+//  bias        as-is, disable, pull-up or pull-down
+//  drive       push-pull, open-drain or open-source
+//  edge        none, rising, falling or both
+//
+// Line attributes override the chip attributes and the top level consumer.
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/warthog618/gpiod"
+	"gopkg.in/yaml.v2"
+)
+
+// Config describes the lines used by a device.
+type Config struct {
+	// The default consumer label for all lines.
+	Consumer string `json:"consumer,omitempty" yaml:"consumer,omitempty" toml:"consumer,omitempty"`
+
+	// The chip level defaults, keyed by chip name, path or label.
+	Chips map[string]Chip `json:"chips,omitempty" yaml:"chips,omitempty" toml:"chips,omitempty"`
+
+	// The lines, keyed by logical name.
+	Lines map[string]Line `json:"lines" yaml:"lines" toml:"lines"`
+}
+
+// Chip describes the defaults for lines on a chip.
+type Chip struct {
+	ActiveLow bool   `json:"active-low,omitempty" yaml:"active-low,omitempty" toml:"active-low,omitempty"`
+	Bias      string `json:"bias,omitempty" yaml:"bias,omitempty" toml:"bias,omitempty"`
+}
+
+// Line describes the location and configuration of a line.
+type Line struct {
+	Chip      string `json:"chip,omitempty" yaml:"chip,omitempty" toml:"chip,omitempty"`
+	Line      string `json:"line,omitempty" yaml:"line,omitempty" toml:"line,omitempty"`
+	Offset    *int   `json:"offset,omitempty" yaml:"offset,omitempty" toml:"offset,omitempty"`
+	Consumer  string `json:"consumer,omitempty" yaml:"consumer,omitempty" toml:"consumer,omitempty"`
+	Direction string `json:"direction,omitempty" yaml:"direction,omitempty" toml:"direction,omitempty"`
+	Value     int    `json:"value,omitempty" yaml:"value,omitempty" toml:"value,omitempty"`
+	ActiveLow *bool  `json:"active-low,omitempty" yaml:"active-low,omitempty" toml:"active-low,omitempty"`
+	Bias      string `json:"bias,omitempty" yaml:"bias,omitempty" toml:"bias,omitempty"`
+	Drive     string `json:"drive,omitempty" yaml:"drive,omitempty" toml:"drive,omitempty"`
+	Edge      string `json:"edge,omitempty" yaml:"edge,omitempty" toml:"edge,omitempty"`
+}
+
+// Load reads the configuration from a file.
+//
+// Files with a .json extension are parsed as JSON, those with a .toml
+// extension as TOML, and all others as YAML.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseJSON(data)
+	case ".toml":
+		return ParseTOML(data)
+	}
+	return ParseYAML(data)
+}
+
+// ParseJSON parses the configuration from JSON.
+//
+// Unknown fields are rejected.
+func ParseJSON(data []byte) (*Config, error) {
+	cfg := Config{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// ParseYAML parses the configuration from YAML.
+//
+// Unknown fields are rejected.
+func ParseYAML(data []byte) (*Config, error) {
+	cfg := Config{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// ParseTOML parses the configuration from TOML.
+//
+// Unknown fields are rejected.
+func ParseTOML(data []byte) (*Config, error) {
+	cfg := Config{}
+	md, err := toml.Decode(string(data), &cfg)
+	if err != nil {
+		return nil, err
+	}
+	if u := md.Undecoded(); len(u) != 0 {
+		return nil, ErrorUnknownField{u[0].String()}
+	}
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Names returns the logical names of the lines, in sorted order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Lines))
+	for name := range c.Lines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChipOptions returns the options for the chip, which may be identified by
+// name, path or label.
+//
+// Chips not described in the configuration only receive the consumer.
+func (c *Config) ChipOptions(chip string) []gpiod.ChipOption {
+	opts := []gpiod.ChipOption(nil)
+	if len(c.Consumer) != 0 {
+		opts = append(opts, gpiod.WithConsumer(c.Consumer))
+	}
+	cc, ok := c.Chips[chip]
+	if !ok {
+		return opts
+	}
+	if cc.ActiveLow {
+		opts = append(opts, gpiod.AsActiveLow)
+	}
+	if bo := biasOptions[cc.Bias]; bo != nil {
+		opts = append(opts, *bo)
+	}
+	return opts
+}
+
+// Options returns the options for the line.
+//
+// Edge events, if enabled, are passed to the handler.
+func (l Line) Options(eh gpiod.EventHandler) ([]gpiod.LineOption, error) {
+	if err := l.check(); err != nil {
+		return nil, err
+	}
+	opts := []gpiod.LineOption(nil)
+	if len(l.Consumer) != 0 {
+		opts = append(opts, gpiod.WithConsumer(l.Consumer))
+	}
+	switch l.Direction {
+	case "input":
+		opts = append(opts, gpiod.AsInput)
+	case "output":
+		opts = append(opts, gpiod.AsOutput(l.Value))
+	}
+	if l.ActiveLow != nil {
+		if *l.ActiveLow {
+			opts = append(opts, gpiod.AsActiveLow)
+		} else {
+			opts = append(opts, gpiod.AsActiveHigh)
+		}
+	}
+	if bo := biasOptions[l.Bias]; bo != nil {
+		opts = append(opts, *bo)
+	}
+	if do := driveOptions[l.Drive]; do != nil {
+		opts = append(opts, *do)
+	}
+	if ef := edgeOptions[l.Edge]; ef != nil {
+		if eh == nil {
+			eh = func(gpiod.LineEvent) {}
+		}
+		opts = append(opts, ef(eh))
+	}
+	return opts, nil
+}
+
+var biasOptions = map[string]*gpiod.BiasOption{
+	"":          nil,
+	"as-is":     nil,
+	"disable":   &gpiod.WithBiasDisable,
+	"pull-up":   &gpiod.WithPullUp,
+	"pull-down": &gpiod.WithPullDown,
+}
+
+var driveOptions = map[string]*gpiod.DriveOption{
+	"":            nil,
+	"push-pull":   &gpiod.AsPushPull,
+	"open-drain":  &gpiod.AsOpenDrain,
+	"open-source": &gpiod.AsOpenSource,
+}
+
+var edgeOptions = map[string]func(func(gpiod.LineEvent)) gpiod.EdgeOption{
+	"":        nil,
+	"none":    nil,
+	"rising":  gpiod.WithRisingEdge,
+	"falling": gpiod.WithFallingEdge,
+	"both":    gpiod.WithBothEdges,
+}
+
+// check performs the validation of the configuration that does not depend on
+// the chips.
+func (c *Config) check() error {
+	for name, cc := range c.Chips {
+		if _, ok := biasOptions[cc.Bias]; !ok {
+			return ErrorChip{name, ErrorBadValue{"bias", cc.Bias}}
+		}
+	}
+	if len(c.Lines) == 0 {
+		return ErrNoLines
+	}
+	for _, name := range c.Names() {
+		if err := c.Lines[name].check(); err != nil {
+			return ErrorLine{name, err}
+		}
+	}
+	return nil
+}
+
+func (l Line) check() error {
+	if len(l.Line) == 0 && l.Offset == nil {
+		return ErrNoLine
+	}
+	if len(l.Line) != 0 && l.Offset != nil {
+		return ErrorConflict{"line", "offset"}
+	}
+	if l.Offset != nil && len(l.Chip) == 0 {
+		return ErrNoChip
+	}
+	switch l.Direction {
+	case "", "as-is", "input", "output":
+	default:
+		return ErrorBadValue{"direction", l.Direction}
+	}
+	if l.Value != 0 && l.Value != 1 {
+		return ErrorBadValue{"value", fmt.Sprint(l.Value)}
+	}
+	if _, ok := biasOptions[l.Bias]; !ok {
+		return ErrorBadValue{"bias", l.Bias}
+	}
+	if _, ok := driveOptions[l.Drive]; !ok {
+		return ErrorBadValue{"drive", l.Drive}
+	}
+	if _, ok := edgeOptions[l.Edge]; !ok {
+		return ErrorBadValue{"edge", l.Edge}
+	}
+	if l.Value != 0 && l.Direction != "output" {
+		return ErrorConflict{"value", "direction " + l.direction()}
+	}
+	if len(l.Drive) != 0 && l.Direction != "output" {
+		return ErrorConflict{"drive", "direction " + l.direction()}
+	}
+	if edgeOptions[l.Edge] != nil && l.Direction == "output" {
+		return ErrorConflict{"edge", "direction output"}
+	}
+	if biasOptions[l.Bias] != nil && l.direction() == "as-is" && edgeOptions[l.Edge] == nil {
+		return ErrorConflict{"bias", "direction as-is"}
+	}
+	return nil
+}
+
+func (l Line) direction() string {
+	if len(l.Direction) == 0 {
+		return "as-is"
+	}
+	return l.Direction
+}
+
+var (
+	// ErrNoLines indicates the configuration does not contain any lines.
+	ErrNoLines = errors.New("no lines specified")
+
+	// ErrNoLine indicates a line specifies neither a line name nor offset.
+	ErrNoLine = errors.New("no line or offset specified")
+
+	// ErrNoChip indicates a line specifies an offset but no chip.
+	ErrNoChip = errors.New("no chip specified for line offset")
+
+	// ErrDuplicateChip indicates two chips in the configuration refer to the
+	// same chip.
+	ErrDuplicateChip = errors.New("duplicate chip")
+
+	// ErrDuplicateLine indicates two lines in the configuration refer to the
+	// same line on a chip.
+	ErrDuplicateLine = errors.New("duplicate line")
+)
+
+// ErrorLine indicates an error in the configuration of a particular line.
+type ErrorLine struct {
+	Name string
+	Err  error
+}
+
+func (e ErrorLine) Error() string {
+	return fmt.Sprintf("line %s: %s", e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrorLine) Unwrap() error {
+	return e.Err
+}
+
+// ErrorChip indicates an error in the configuration of a particular chip.
+type ErrorChip struct {
+	Name string
+	Err  error
+}
+
+func (e ErrorChip) Error() string {
+	return fmt.Sprintf("chip %s: %s", e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrorChip) Unwrap() error {
+	return e.Err
+}
+
+// ErrorBadValue indicates an attribute has an invalid value.
+type ErrorBadValue struct {
+	Attr  string
+	Value string
+}
+
+func (e ErrorBadValue) Error() string {
+	return fmt.Sprintf("invalid %s '%s'", e.Attr, e.Value)
+}
+
+// ErrorUnknownField indicates the configuration contains an unrecognised
+// field.
+type ErrorUnknownField struct {
+	Field string
+}
+
+func (e ErrorUnknownField) Error() string {
+	return fmt.Sprintf("unknown field '%s'", e.Field)
+}
+
+// ErrorConflict indicates a line contains conflicting attributes.
+type ErrorConflict struct {
+	Attr1 string
+	Attr2 string
+}
+
+func (e ErrorConflict) Error() string {
+	return fmt.Sprintf("conflicting attributes '%s' and '%s'", e.Attr1, e.Attr2)
+}
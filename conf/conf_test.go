@@ -0,0 +1,394 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package conf_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/conf"
+	"github.com/warthog618/gpiod/mockup"
+	"golang.org/x/sys/unix"
+)
+
+var yamlConfig = `
+consumer: myapp
+chips:
+  gpiochip0:
+    active-low: true
+    bias: pull-up
+lines:
+  relay:
+    chip: gpiochip0
+    offset: 4
+    direction: output
+    value: 1
+    drive: open-drain
+  door:
+    line: DOOR_SENSE
+    direction: input
+    active-low: false
+    edge: both
+`
+
+var jsonConfig = `{
+  "consumer": "myapp",
+  "chips": {"gpiochip0": {"active-low": true, "bias": "pull-up"}},
+  "lines": {
+    "relay": {"chip": "gpiochip0", "offset": 4, "direction": "output", "value": 1, "drive": "open-drain"},
+    "door": {"line": "DOOR_SENSE", "direction": "input", "active-low": false, "edge": "both"}
+  }
+}`
+
+var tomlConfig = `
+consumer = "myapp"
+
+[chips.gpiochip0]
+active-low = true
+bias = "pull-up"
+
+[lines.relay]
+chip = "gpiochip0"
+offset = 4
+direction = "output"
+value = 1
+drive = "open-drain"
+
+[lines.door]
+line = "DOOR_SENSE"
+direction = "input"
+active-low = false
+edge = "both"
+`
+
+func expectedConfig() *conf.Config {
+	offset := 4
+	activeLow := false
+	return &conf.Config{
+		Consumer: "myapp",
+		Chips: map[string]conf.Chip{
+			"gpiochip0": {ActiveLow: true, Bias: "pull-up"},
+		},
+		Lines: map[string]conf.Line{
+			"relay": {
+				Chip:      "gpiochip0",
+				Offset:    &offset,
+				Direction: "output",
+				Value:     1,
+				Drive:     "open-drain",
+			},
+			"door": {
+				Line:      "DOOR_SENSE",
+				Direction: "input",
+				ActiveLow: &activeLow,
+				Edge:      "both",
+			},
+		},
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	cfg, err := conf.ParseYAML([]byte(yamlConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, expectedConfig(), cfg)
+	assert.Equal(t, []string{"door", "relay"}, cfg.Names())
+
+	cfg, err = conf.ParseYAML([]byte("lines:\n  a:\n    line: A\n    sideways: true\n"))
+	assert.NotNil(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestParseJSON(t *testing.T) {
+	cfg, err := conf.ParseJSON([]byte(jsonConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, expectedConfig(), cfg)
+
+	cfg, err = conf.ParseJSON([]byte(`{"lines": {"a": {"line": "A", "sideways": true}}}`))
+	assert.NotNil(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestParseTOML(t *testing.T) {
+	cfg, err := conf.ParseTOML([]byte(tomlConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, expectedConfig(), cfg)
+
+	cfg, err = conf.ParseTOML([]byte("[lines.a]\nline = \"A\"\nsideways = true\n"))
+	assert.Equal(t, conf.ErrorUnknownField{"lines.a.sideways"}, err)
+	assert.Nil(t, cfg)
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf_test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	patterns := []struct {
+		name string
+		data string
+	}{
+		{"dev.yaml", yamlConfig},
+		{"dev.yml", yamlConfig},
+		{"dev.json", jsonConfig},
+		{"dev.toml", tomlConfig},
+		{"dev", jsonConfig}, // YAML is a superset of JSON
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			path := filepath.Join(dir, p.name)
+			err := ioutil.WriteFile(path, []byte(p.data), 0644)
+			require.Nil(t, err)
+			cfg, err := conf.Load(path)
+			assert.Nil(t, err)
+			assert.Equal(t, expectedConfig(), cfg)
+		}
+		t.Run(p.name, tf)
+	}
+	cfg, err := conf.Load(filepath.Join(dir, "nonexistent.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, cfg)
+}
+
+func TestParseInvalid(t *testing.T) {
+	patterns := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"no lines", "consumer: myapp\n", conf.ErrNoLines},
+		{"bad chip bias",
+			"chips:\n  c:\n    bias: sideways\nlines:\n  a:\n    line: A\n",
+			conf.ErrorChip{"c", conf.ErrorBadValue{"bias", "sideways"}}},
+		{"no line", "lines:\n  a:\n    chip: c\n",
+			conf.ErrorLine{"a", conf.ErrNoLine}},
+		{"line and offset", "lines:\n  a:\n    chip: c\n    line: A\n    offset: 1\n",
+			conf.ErrorLine{"a", conf.ErrorConflict{"line", "offset"}}},
+		{"offset without chip", "lines:\n  a:\n    offset: 1\n",
+			conf.ErrorLine{"a", conf.ErrNoChip}},
+		{"bad direction", "lines:\n  a:\n    line: A\n    direction: up\n",
+			conf.ErrorLine{"a", conf.ErrorBadValue{"direction", "up"}}},
+		{"bad value", "lines:\n  a:\n    line: A\n    direction: output\n    value: 2\n",
+			conf.ErrorLine{"a", conf.ErrorBadValue{"value", "2"}}},
+		{"bad bias", "lines:\n  a:\n    line: A\n    bias: up\n",
+			conf.ErrorLine{"a", conf.ErrorBadValue{"bias", "up"}}},
+		{"bad drive", "lines:\n  a:\n    line: A\n    direction: output\n    drive: hard\n",
+			conf.ErrorLine{"a", conf.ErrorBadValue{"drive", "hard"}}},
+		{"bad edge", "lines:\n  a:\n    line: A\n    edge: sharp\n",
+			conf.ErrorLine{"a", conf.ErrorBadValue{"edge", "sharp"}}},
+		{"value on input", "lines:\n  a:\n    line: A\n    direction: input\n    value: 1\n",
+			conf.ErrorLine{"a", conf.ErrorConflict{"value", "direction input"}}},
+		{"drive on as-is", "lines:\n  a:\n    line: A\n    drive: open-drain\n",
+			conf.ErrorLine{"a", conf.ErrorConflict{"drive", "direction as-is"}}},
+		{"bias on as-is", "lines:\n  a:\n    line: A\n    bias: pull-up\n",
+			conf.ErrorLine{"a", conf.ErrorConflict{"bias", "direction as-is"}}},
+		{"edge on output", "lines:\n  a:\n    line: A\n    direction: output\n    edge: both\n",
+			conf.ErrorLine{"a", conf.ErrorConflict{"edge", "direction output"}}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			cfg, err := conf.ParseYAML([]byte(p.data))
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, cfg)
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestChipOptions(t *testing.T) {
+	cfg := expectedConfig()
+	opts := cfg.ChipOptions("gpiochip0")
+	assert.Equal(t, []gpiod.ChipOption{
+		gpiod.WithConsumer("myapp"),
+		gpiod.AsActiveLow,
+		gpiod.WithPullUp,
+	}, opts)
+	opts = cfg.ChipOptions("gpiochip1")
+	assert.Equal(t, []gpiod.ChipOption{gpiod.WithConsumer("myapp")}, opts)
+}
+
+func TestLineOptions(t *testing.T) {
+	cfg := expectedConfig()
+	opts, err := cfg.Lines["relay"].Options(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiod.LineOption{
+		gpiod.AsOutput(1),
+		gpiod.AsOpenDrain,
+	}, opts)
+
+	opts, err = cfg.Lines["door"].Options(nil)
+	assert.Nil(t, err)
+	require.Equal(t, 3, len(opts))
+	assert.Equal(t, gpiod.AsInput, opts[0])
+	assert.Equal(t, gpiod.AsActiveHigh, opts[1])
+	assert.IsType(t, gpiod.EdgeOption{}, opts[2])
+
+	l := conf.Line{Line: "A", Edge: "rising"}
+	opts, err = l.Options(nil)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(opts))
+	assert.IsType(t, gpiod.EdgeOption{}, opts[0])
+
+	l = conf.Line{Line: "A", Direction: "sideways"}
+	opts, err = l.Options(nil)
+	assert.Equal(t, conf.ErrorBadValue{"direction", "sideways"}, err)
+	assert.Nil(t, opts)
+}
+
+func TestValidate(t *testing.T) {
+	m, err := mockup.New([]int{8, 8}, true)
+	require.Nil(t, err)
+	defer m.Close()
+	mc, err := m.Chip(0)
+	require.Nil(t, err)
+
+	patterns := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"valid",
+			"chips:\n  gpio-mockup-A:\n    bias: pull-up\n" +
+				"lines:\n  a:\n    chip: gpio-mockup-A\n    offset: 1\n" +
+				"  b:\n    line: gpio-mockup-B-2\n" +
+				"  c:\n    chip: " + mc.Name + "\n    line: gpio-mockup-A-3\n",
+			nil},
+		{"unknown line", "lines:\n  a:\n    line: nonexistent\n",
+			conf.ErrorLine{"a", gpiod.ErrLineNotFound}},
+		{"line not on chip", "lines:\n  a:\n    chip: gpio-mockup-A\n    line: gpio-mockup-B-1\n",
+			conf.ErrorLine{"a", gpiod.ErrLineNotFound}},
+		{"bad offset", "lines:\n  a:\n    chip: gpio-mockup-A\n    offset: 8\n",
+			conf.ErrorLine{"a", gpiod.ErrInvalidOffset}},
+		{"duplicate line",
+			"lines:\n  a:\n    chip: gpio-mockup-A\n    offset: 1\n  b:\n    line: gpio-mockup-A-1\n",
+			conf.ErrorLine{"b", conf.ErrDuplicateLine}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			cfg, err := conf.ParseYAML([]byte(p.data))
+			require.Nil(t, err)
+			err = cfg.Validate()
+			assert.Equal(t, p.err, err)
+		}
+		t.Run(p.name, tf)
+	}
+
+	cfg, err := conf.ParseYAML([]byte(
+		"chips:\n  nonexistent:\n    bias: pull-up\nlines:\n  a:\n    line: gpio-mockup-A-1\n"))
+	require.Nil(t, err)
+	err = cfg.Validate()
+	require.IsType(t, conf.ErrorChip{}, err)
+	assert.Equal(t, "nonexistent", err.(conf.ErrorChip).Name)
+}
+
+func TestRequest(t *testing.T) {
+	m, err := mockup.New([]int{8, 8}, true)
+	require.Nil(t, err)
+	defer m.Close()
+	mca, err := m.Chip(0)
+	require.Nil(t, err)
+	mcb, err := m.Chip(1)
+	require.Nil(t, err)
+
+	cfg, err := conf.ParseYAML([]byte(`
+consumer: conf-test
+lines:
+  relay:
+    chip: gpio-mockup-A
+    offset: 2
+    direction: output
+    value: 1
+  door:
+    line: gpio-mockup-B-3
+    consumer: door-sensor
+    edge: both
+`))
+	require.Nil(t, err)
+	ich := make(chan gpiod.LineEvent, 3)
+	d, err := cfg.Request(func(evt gpiod.LineEvent) {
+		ich <- evt
+	})
+	require.Nil(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, []string{"door", "relay"}, d.Names())
+	assert.Nil(t, d.Line("nonexistent"))
+
+	relay := d.Line("relay")
+	require.NotNil(t, relay)
+	assert.Equal(t, mca.Name, relay.Chip())
+	assert.Equal(t, 2, relay.Offset())
+	v, err := mca.Value(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	inf, err := relay.Info()
+	assert.Nil(t, err)
+	assert.Equal(t, "conf-test", inf.Consumer)
+	assert.True(t, inf.IsOut)
+
+	door := d.Line("door")
+	require.NotNil(t, door)
+	assert.Equal(t, mcb.Name, door.Chip())
+	assert.Equal(t, 3, door.Offset())
+	inf, err = door.Info()
+	assert.Nil(t, err)
+	assert.Equal(t, "door-sensor", inf.Consumer)
+	assert.False(t, inf.IsOut)
+
+	err = mcb.SetValue(3, 1)
+	assert.Nil(t, err)
+	select {
+	case evt := <-ich:
+		assert.Equal(t, "door", evt.Name)
+		assert.Equal(t, mcb.Name, evt.Chip)
+		assert.Equal(t, 3, evt.Offset)
+		assert.Equal(t, gpiod.LineEventRisingEdge, evt.Type)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+
+	err = d.Close()
+	assert.Nil(t, err)
+	_, err = relay.Value()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	_, err = door.Value()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestRequestFailure(t *testing.T) {
+	m, err := mockup.New([]int{8}, true)
+	require.Nil(t, err)
+	defer m.Close()
+	mc, err := m.Chip(0)
+	require.Nil(t, err)
+	c, err := gpiod.NewChip(mc.Name)
+	require.Nil(t, err)
+	defer c.Close()
+	l, err := c.RequestLine(3)
+	require.Nil(t, err)
+	defer l.Close()
+
+	cfg, err := conf.ParseYAML([]byte(`
+lines:
+  a:
+    line: gpio-mockup-A-2
+  b:
+    line: gpio-mockup-A-3
+`))
+	require.Nil(t, err)
+	d, err := cfg.Request(nil)
+	assert.Equal(t, conf.ErrorLine{"b", unix.EBUSY}, err)
+	assert.Nil(t, d)
+
+	// a was released
+	inf, err := c.LineInfo(2)
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+}
@@ -0,0 +1,242 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package conf
+
+import (
+	"github.com/warthog618/gpiod"
+)
+
+// Device is the set of lines requested for a configuration.
+type Device struct {
+	names []string
+	lines map[string]*gpiod.Line
+}
+
+// Validate checks the configuration against the chips on the system.
+//
+// This checks that all chips and lines exist, and that no line is referred to
+// more than once.  It does not check that the lines are available to be
+// requested.
+func (c *Config) Validate() error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	cs, err := c.newChipSet()
+	if err != nil {
+		return err
+	}
+	defer cs.close()
+	_, err = cs.resolve()
+	return err
+}
+
+// Request validates the configuration and requests all the lines it
+// describes.
+//
+// Edge events from any lines with edge detection enabled are passed to the
+// handler, tagged with the logical name of the line.
+//
+// If any line cannot be requested then any lines already requested are
+// released.
+func (c *Config) Request(eh gpiod.EventHandler) (*Device, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	cs, err := c.newChipSet()
+	if err != nil {
+		return nil, err
+	}
+	defer cs.close()
+	rr, err := cs.resolve()
+	if err != nil {
+		return nil, err
+	}
+	d := Device{
+		names: c.Names(),
+		lines: make(map[string]*gpiod.Line, len(rr)),
+	}
+	for _, r := range rr {
+		opts, _ := c.Lines[r.name].Options(namedHandler(r.name, eh))
+		l, err := r.chip.RequestLine(r.offset, opts...)
+		if err != nil {
+			d.Close()
+			return nil, ErrorLine{r.name, err}
+		}
+		d.lines[r.name] = l
+	}
+	return &d, nil
+}
+
+func namedHandler(name string, eh gpiod.EventHandler) gpiod.EventHandler {
+	if eh == nil {
+		return nil
+	}
+	return func(evt gpiod.LineEvent) {
+		evt.Name = name
+		eh(evt)
+	}
+}
+
+// Close releases all the lines requested for the device.
+//
+// Returns the first error encountered, if any.
+func (d *Device) Close() error {
+	var err error
+	for _, l := range d.lines {
+		if lerr := l.Close(); lerr != nil && err == nil {
+			err = lerr
+		}
+	}
+	return err
+}
+
+// Line returns the requested line with the logical name, or nil if there is
+// no such line.
+func (d *Device) Line(name string) *gpiod.Line {
+	return d.lines[name]
+}
+
+// Names returns the logical names of the lines, in sorted order.
+func (d *Device) Names() []string {
+	return d.names
+}
+
+// chipSet is the set of chips referred to by a configuration.
+type chipSet struct {
+	cfg *Config
+
+	// the open chips, keyed by chip name.
+	chips map[string]*gpiod.Chip
+
+	// the chip name for each chip identifier in the configuration.
+	names map[string]string
+
+	// the key into cfg.Chips for each chip name, if any.
+	keys map[string]string
+}
+
+func (c *Config) newChipSet() (*chipSet, error) {
+	cs := chipSet{
+		cfg:   c,
+		chips: map[string]*gpiod.Chip{},
+		names: map[string]string{},
+		keys:  map[string]string{},
+	}
+	for key := range c.Chips {
+		name, err := cs.chipName(key)
+		if err != nil {
+			return nil, ErrorChip{key, err}
+		}
+		if _, ok := cs.keys[name]; ok {
+			return nil, ErrorChip{key, ErrDuplicateChip}
+		}
+		cs.keys[name] = key
+	}
+	return &cs, nil
+}
+
+func (cs *chipSet) close() {
+	for _, c := range cs.chips {
+		c.Close()
+	}
+}
+
+// chipName returns the name of the chip with the identifier, which may be the
+// name, path or label of the chip.
+func (cs *chipSet) chipName(id string) (string, error) {
+	if name, ok := cs.names[id]; ok {
+		return name, nil
+	}
+	name := ""
+	c, err := gpiod.NewChip(id)
+	if err == nil {
+		name = c.Name
+		c.Close()
+	} else {
+		var lerr error
+		if name, lerr = gpiod.FindChipByLabel(id); lerr != nil {
+			return "", err
+		}
+	}
+	cs.names[id] = name
+	return name, nil
+}
+
+// open returns the chip with the identifier, opening it with the options
+// from the configuration if it is not already open.
+func (cs *chipSet) open(id string) (*gpiod.Chip, error) {
+	name, err := cs.chipName(id)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := cs.chips[name]; ok {
+		return c, nil
+	}
+	c, err := gpiod.NewChip(name, cs.cfg.ChipOptions(cs.keys[name])...)
+	if err != nil {
+		return nil, err
+	}
+	cs.chips[name] = c
+	return c, nil
+}
+
+// resolved is a line from the configuration located on its chip.
+type resolved struct {
+	name   string
+	chip   *gpiod.Chip
+	offset int
+}
+
+// resolve locates all the lines in the configuration, in order of name.
+func (cs *chipSet) resolve() ([]resolved, error) {
+	rr := []resolved(nil)
+	found := map[gpiod.ChipLine]bool{}
+	for _, name := range cs.cfg.Names() {
+		r, err := cs.resolveLine(name)
+		if err != nil {
+			return nil, ErrorLine{name, err}
+		}
+		cl := gpiod.ChipLine{Chip: r.chip.Name, Offset: r.offset}
+		if found[cl] {
+			return nil, ErrorLine{name, ErrDuplicateLine}
+		}
+		found[cl] = true
+		rr = append(rr, r)
+	}
+	return rr, nil
+}
+
+func (cs *chipSet) resolveLine(name string) (resolved, error) {
+	l := cs.cfg.Lines[name]
+	r := resolved{name: name}
+	id := l.Chip
+	if len(id) == 0 {
+		cname, o, err := gpiod.FindLine(l.Line)
+		if err != nil {
+			return r, err
+		}
+		id = cname
+		r.offset = o
+	}
+	c, err := cs.open(id)
+	if err != nil {
+		return r, err
+	}
+	r.chip = c
+	switch {
+	case l.Offset != nil:
+		r.offset = *l.Offset
+		if r.offset < 0 || r.offset >= c.Lines() {
+			return r, gpiod.ErrInvalidOffset
+		}
+	case len(l.Chip) != 0:
+		if r.offset, err = c.FindLine(l.Line); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+const driveFlags = uapi.HandleRequestOpenDrain | uapi.HandleRequestOpenSource
+
+// unix.ENOTSUPP is not defined, but the kernel may return it from drivers.
+const errnoNotSupp = unix.Errno(524)
+
+// isDriveUnsupported returns true if the error indicates the kernel rejected
+// the drive flags.
+func isDriveUnsupported(err error) bool {
+	return err == unix.EINVAL || err == unix.EOPNOTSUPP || err == errnoNotSupp
+}
+
+// canEmulate returns true if the drive of the lines can be emulated with the
+// options.
+func canEmulate(lo LineOptions) bool {
+	return (lo.emulate == nil || !lo.emulate.disable) &&
+		lo.HandleFlags&driveFlags != 0
+}
+
+// isForced returns true if the drive is to be emulated even if supported by
+// the kernel.
+func isForced(lo LineOptions) bool {
+	return lo.emulate != nil && lo.emulate.force
+}
+
+// emulatedFlags returns the flags that emulate the drive for a line with the
+// given active state.
+//
+// The line is set to input when it would float, and output otherwise.
+func emulatedFlags(flags, drive uapi.HandleFlag, value int) uapi.HandleFlag {
+	high := (value != 0) != flags.IsActiveLow()
+	flags &= ^(driveFlags | uapi.HandleRequestInput | uapi.HandleRequestOutput)
+	if high == (drive == uapi.HandleRequestOpenDrain) {
+		return flags | uapi.HandleRequestInput
+	}
+	return flags | uapi.HandleRequestOutput
+}
+
+// valueAt returns the value for the line at the given index, with lines
+// beyond the end of the values defaulting to 0.
+func valueAt(values []int, idx int) int {
+	if idx >= len(values) {
+		return 0
+	}
+	return values[idx]
+}
+
+// requestEmulated requests the lines with the drive emulated.
+//
+// As the lines of a request share a direction, multiple lines are split into
+// a request for each line.
+func (l *baseLine) requestEmulated(c *Chip, lo LineOptions) error {
+	drive := lo.HandleFlags & driveFlags
+	fds := make([]uintptr, len(l.offsets))
+	for i, o := range l.offsets {
+		v := valueAt(lo.InitialValues, i)
+		elo := lo
+		elo.HandleFlags = emulatedFlags(lo.HandleFlags, drive, v)
+		elo.InitialValues = []int{v}
+		fd, err := c.getHandleRequest([]int{o}, elo)
+		if err != nil {
+			for _, fd := range fds[:i] {
+				unix.Close(int(fd))
+			}
+			return err
+		}
+		fds[i] = fd
+	}
+	l.vfd = fds[0]
+	if len(fds) > 1 {
+		l.split = fds
+	}
+	l.emulated = drive
+	return nil
+}
+
+// handles returns the fds of the handle requests for the lines.
+//
+// Lines split to emulate the drive have a request for each line, else the
+// one request covers all the lines.
+func (l *baseLine) handles() []uintptr {
+	if l.split != nil {
+		return l.split
+	}
+	return []uintptr{l.vfd}
+}
+
+// canSplit returns true if each line has its own request, as required to
+// emulate the drive.
+func (l *baseLine) canSplit() bool {
+	return l.split != nil || len(l.offsets) == 1
+}
+
+// readValues reads the values of the lines from the handle requests.
+func (l *baseLine) readValues(values *uapi.HandleData) error {
+	if l.split == nil {
+		return uapi.GetLineValues(l.vfd, values)
+	}
+	for i, fd := range l.split {
+		var vv uapi.HandleData
+		if err := uapi.GetLineValues(fd, &vv); err != nil {
+			return err
+		}
+		values[i] = vv[0]
+	}
+	return nil
+}
+
+// writeValues sets the values of the lines, switching lines with emulated
+// drive between input and output as required.
+//
+// The current direction of lines with emulated drive is determined from the
+// output values, so they must not be updated until the write succeeds.
+//
+// Assumes l is locked.
+func (l *baseLine) writeValues(values uapi.HandleData) error {
+	if l.emulated == 0 && l.split == nil {
+		return uapi.SetLineValues(l.vfd, values)
+	}
+	for i, fd := range l.handles() {
+		f := l.flags
+		if l.emulated != 0 {
+			f = emulatedFlags(l.flags, l.emulated, int(values[i]))
+			prev := emulatedFlags(l.flags, l.emulated, valueAt(l.outputValues, i))
+			if f != prev {
+				hc := uapi.HandleConfig{Flags: f}
+				hc.DefaultValues[0] = values[i]
+				if err := uapi.SetLineConfig(fd, &hc); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if f.IsInput() {
+			// floating
+			continue
+		}
+		if err := uapi.SetLineValues(fd, uapi.HandleData{values[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeConfig applies the flags and values to the handle requests of the
+// lines.
+func (l *baseLine) writeConfig(flags uapi.HandleFlag, values []int) error {
+	if l.split == nil {
+		hc := uapi.HandleConfig{Flags: flags}
+		for i, v := range values {
+			hc.DefaultValues[i] = uint8(v)
+		}
+		return uapi.SetLineConfig(l.vfd, &hc)
+	}
+	for i, fd := range l.split {
+		hc := uapi.HandleConfig{Flags: flags}
+		hc.DefaultValues[0] = uint8(valueAt(values, i))
+		if err := uapi.SetLineConfig(fd, &hc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconfigureEmulated applies the configuration to the lines with the drive
+// emulated.
+//
+// Assumes l is locked.
+func (l *baseLine) reconfigureEmulated(lo LineOptions) error {
+	if !l.canSplit() {
+		return ErrEmulationConflict
+	}
+	drive := lo.HandleFlags & driveFlags
+	for i, fd := range l.handles() {
+		v := valueAt(lo.InitialValues, i)
+		hc := uapi.HandleConfig{Flags: emulatedFlags(lo.HandleFlags, drive, v)}
+		hc.DefaultValues[0] = uint8(v)
+		if err := uapi.SetLineConfig(fd, &hc); err != nil {
+			return err
+		}
+	}
+	l.flags = lo.HandleFlags
+	l.outputValues = lo.InitialValues
+	l.emulated = drive
+	return nil
+}
+
+// closeHandles closes the handle requests for the lines.
+func (l *baseLine) closeHandles() {
+	for _, fd := range l.handles() {
+		unix.Close(int(fd))
+	}
+}
@@ -26,7 +26,7 @@ func main() {
 	l, err := c.RequestLine(offset,
 		gpiod.WithPullUp,
 		gpiod.WithBothEdges(func(evt gpiod.LineEvent) {
-			t := time.Now()
+			t := evt.Time()
 			edge := "rising"
 			if evt.Type == gpiod.LineEventFallingEdge {
 				edge = "falling"
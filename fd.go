@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/warthog618/gpiod/uapi"
+)
+
+// NewChipFromFd creates a Chip from the file descriptor of an already open
+// GPIO character device.
+//
+// This is intended for processes that are provided with open devices, such as
+// by socket activation or fd passing, or which are sandboxed and cannot open
+// the device themselves.
+//
+// On success the Chip takes ownership of the fd, which is closed when the Chip
+// is closed.
+func NewChipFromFd(fd uintptr, options ...ChipOption) (*Chip, error) {
+	ci, err := uapi.GetChipInfo(fd)
+	if err != nil {
+		return nil, ErrNotCharacterDevice
+	}
+	co := ChipOptions{
+		consumer: fmt.Sprintf("gpiod-%d", os.Getpid()),
+	}
+	for _, option := range options {
+		option.applyChipOption(&co)
+	}
+	c := Chip{
+		f:       os.NewFile(fd, "/dev/"+uapi.BytesToString(ci.Name[:])),
+		Name:    uapi.BytesToString(ci.Name[:]),
+		Label:   uapi.BytesToString(ci.Label[:]),
+		lines:   int(ci.Lines),
+		options: co,
+	}
+	if len(c.Label) == 0 {
+		c.Label = "unknown"
+	}
+	c.Parent = chipParent(c.Name)
+	c.OfNode = chipOfNode(c.Name)
+	return &c, nil
+}
+
+// LineFromFd creates a Line from the file descriptor of an existing request
+// for the line on the chip.
+//
+// Refer to LinesFromFds for details.
+func (c *Chip) LineFromFd(fd uintptr, offset int, options ...LineOption) (*Line, error) {
+	ll, err := c.linesFromFds([]uintptr{fd}, []int{offset}, options)
+	if err != nil {
+		return nil, err
+	}
+	l := newLine(ll)
+	policies.track(&l.baseLine)
+	return l, nil
+}
+
+// LinesFromFds creates Lines from the file descriptors of an existing request
+// for the lines on the chip.
+//
+// For a request without edge detection there is a single fd covering all the
+// lines. For a request with edge detection there is one fd per line, in the
+// same order as the offsets.
+//
+// The configuration of the lines, and the values of outputs, are queried from
+// the kernel.  The kernel does not report which lines are covered by a request
+// fd, so the offsets must match those of the original request.
+//
+// The options may provide an event handler for lines with edge detection, as
+// well as the OnClose, WithVerifiedWrites and drive emulation options.
+// Options which would alter the configuration of the lines are ignored - use
+// Reconfigure for that.
+//
+// On success the Lines take ownership of the fds, which are closed when the
+// Lines are closed.
+func (c *Chip) LinesFromFds(fds []uintptr, offsets []int, options ...LineOption) (*Lines, error) {
+	ll, err := c.linesFromFds(fds, offsets, options)
+	if err != nil {
+		return nil, err
+	}
+	policies.track(&ll.baseLine)
+	return ll, nil
+}
+
+// linesFromFds creates the Lines, but does not track their close policy.
+func (c *Chip) linesFromFds(fds []uintptr, offsets []int, options []LineOption) (*Lines, error) {
+	if c.sysfs != nil {
+		return nil, ErrorSysfsUnsupported{"line requests from fds"}
+	}
+	if len(fds) == 0 || len(offsets) == 0 || len(offsets) > uapi.HandlesMax {
+		return nil, ErrNotLineRequest
+	}
+	isEvent, err := isEventRequest(fds[0])
+	if err != nil {
+		return nil, err
+	}
+	if (isEvent && len(fds) != len(offsets)) || (!isEvent && len(fds) != 1) {
+		return nil, ErrNotLineRequest
+	}
+	ifds := make([]int, len(fds))
+	for i, fd := range fds {
+		if e, err := isEventRequest(fd); err != nil || e != isEvent {
+			return nil, ErrNotLineRequest
+		}
+		ifds[i] = int(fd)
+	}
+	h := handoff{
+		Chip:    c.Name,
+		Offsets: append([]int(nil), offsets...),
+		Event:   isEvent,
+	}
+	for i, o := range offsets {
+		info, err := c.LineInfo(o)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Requested {
+			return nil, ErrNotLineRequest
+		}
+		flags := infoHandleFlags(info)
+		if i != 0 && flags != h.Flags {
+			// all lines in a request share the same configuration.
+			return nil, ErrNotLineRequest
+		}
+		h.Flags = flags
+	}
+	if h.Flags.IsOutput() {
+		var vv uapi.HandleData
+		if err = uapi.GetLineValues(fds[0], &vv); err != nil {
+			return nil, err
+		}
+		h.Values = make([]int, len(offsets))
+		for i := range h.Values {
+			h.Values[i] = int(vv[i])
+		}
+	}
+	ll, err := wrapLines(h, ifds, options)
+	if err != nil {
+		return nil, err
+	}
+	ll.origin = c
+	return ll, nil
+}
+
+// The names of the anonymous inodes backing the request fds returned by the
+// kernel, as reported by readlink on the fd.
+const (
+	// a v1 handle request, from GPIO_GET_LINEHANDLE_IOCTL.
+	handleInode = "anon_inode:gpio-linehandle"
+
+	// a v1 event request, from GPIO_GET_LINEEVENT_IOCTL.
+	eventInode = "anon_inode:gpio-event"
+
+	// a v2 line request, from GPIO_V2_GET_LINE_IOCTL.
+	lineInode = "anon_inode:gpio-line"
+)
+
+// isEventRequest determines if the fd is an event request, rather than a
+// handle request.
+//
+// Returns an error if the fd is neither.
+func isEventRequest(fd uintptr) (bool, error) {
+	target, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+	if err != nil {
+		return false, err
+	}
+	switch target {
+	case eventInode:
+		return true, nil
+	case handleInode:
+		return false, nil
+	}
+	return false, ErrNotLineRequest
+}
+
+// infoHandleFlags returns the handle flags corresponding to the line info.
+func infoHandleFlags(info LineInfo) uapi.HandleFlag {
+	flags := uapi.HandleRequestInput
+	if info.IsOut {
+		flags = uapi.HandleRequestOutput
+	}
+	if info.ActiveLow {
+		flags |= uapi.HandleRequestActiveLow
+	}
+	if info.OpenDrain {
+		flags |= uapi.HandleRequestOpenDrain
+	}
+	if info.OpenSource {
+		flags |= uapi.HandleRequestOpenSource
+	}
+	if info.BiasDisable {
+		flags |= uapi.HandleRequestBiasDisable
+	}
+	if info.PullDown {
+		flags |= uapi.HandleRequestPullDown
+	}
+	if info.PullUp {
+		flags |= uapi.HandleRequestPullUp
+	}
+	return flags
+}
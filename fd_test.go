@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+func TestNewChipFromFd(t *testing.T) {
+	fd, err := unix.Open(platform.Devpath(), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	require.Nil(t, err)
+	c, err := gpiod.NewChipFromFd(uintptr(fd), gpiod.WithConsumer("fromfd"))
+	require.Nil(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, platform.Name(), c.Name)
+	assert.Equal(t, platform.Label(), c.Label)
+	assert.Equal(t, platform.Lines(), c.Lines())
+
+	l, err := c.RequestLine(platform.OutLine())
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	inf, err := l.Info()
+	assert.Nil(t, err)
+	assert.Equal(t, "fromfd", inf.Consumer)
+	l.Close()
+
+	err = c.Close()
+	assert.Nil(t, err)
+	// fd closed with chip
+	_, err = uapi.GetChipInfo(uintptr(fd))
+	assert.NotNil(t, err)
+
+	// not a chip
+	fd, err = unix.Open("/dev/null", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	require.Nil(t, err)
+	defer unix.Close(fd)
+	c, err = gpiod.NewChipFromFd(uintptr(fd))
+	assert.Equal(t, gpiod.ErrNotCharacterDevice, err)
+	assert.Nil(t, c)
+}
+
+func getHandleFd(t *testing.T, offsets []int, flags uapi.HandleFlag, values ...uint8) uintptr {
+	t.Helper()
+	fd, err := unix.Open(platform.Devpath(), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	require.Nil(t, err)
+	defer unix.Close(fd)
+	hr := uapi.HandleRequest{
+		Flags: flags,
+		Lines: uint32(len(offsets)),
+	}
+	for i, o := range offsets {
+		hr.Offsets[i] = uint32(o)
+	}
+	copy(hr.DefaultValues[:], values)
+	copy(hr.Consumer[:], "handle")
+	err = uapi.GetLineHandle(uintptr(fd), &hr)
+	require.Nil(t, err)
+	return uintptr(hr.Fd)
+}
+
+func TestLinesFromFds(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offsets := platform.FloatingLines()[:2]
+
+	hfd := getHandleFd(t, offsets,
+		uapi.HandleRequestOutput|uapi.HandleRequestActiveLow, 1, 0)
+	ll, err := c.LinesFromFds([]uintptr{hfd}, offsets)
+	require.Nil(t, err)
+	require.NotNil(t, ll)
+	assert.Equal(t, offsets, ll.Offsets())
+	assert.Equal(t, c.Name, ll.Chip())
+	vv := []int{0, 0}
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0}, vv)
+
+	// reconfigure is relative to the queried config
+	err = ll.Reconfigure(gpiod.AsActiveHigh)
+	assert.Nil(t, err)
+	inf, err := c.LineInfo(offsets[0])
+	assert.Nil(t, err)
+	assert.True(t, inf.IsOut)
+	assert.False(t, inf.ActiveLow)
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0}, vv)
+
+	err = ll.Close()
+	assert.Nil(t, err)
+	inf, err = c.LineInfo(offsets[0])
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+}
+
+func TestLinesFromFdsInvalid(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offsets := platform.FloatingLines()[:2]
+
+	hfd := getHandleFd(t, offsets[:1], uapi.HandleRequestInput)
+	defer unix.Close(int(hfd))
+	nfd, err := unix.Open("/dev/null", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	require.Nil(t, err)
+	defer unix.Close(nfd)
+
+	patterns := []struct {
+		name    string
+		fds     []uintptr
+		offsets []int
+	}{
+		{"no fds", nil, offsets[:1]},
+		{"no offsets", []uintptr{hfd}, nil},
+		{"not request", []uintptr{uintptr(nfd)}, offsets[:1]},
+		{"too many fds", []uintptr{hfd, hfd}, offsets},
+		{"unrequested", []uintptr{hfd}, offsets[1:]},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			ll, err := c.LinesFromFds(p.fds, p.offsets)
+			assert.Equal(t, gpiod.ErrNotLineRequest, err)
+			assert.Nil(t, ll)
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestLineFromFdEvent(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.IntrLine()
+
+	fd, err := unix.Open(platform.Devpath(), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	require.Nil(t, err)
+	er := uapi.EventRequest{
+		Offset:      uint32(offset),
+		HandleFlags: uapi.HandleRequestInput,
+		EventFlags:  uapi.EventRequestBothEdges,
+	}
+	err = uapi.GetLineEvent(uintptr(fd), &er)
+	unix.Close(fd)
+	require.Nil(t, err)
+
+	ich := make(chan gpiod.LineEvent, 3)
+	l, err := c.LineFromFd(uintptr(er.Fd), offset,
+		gpiod.WithBothEdges(func(evt gpiod.LineEvent) {
+			ich <- evt
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	assert.Equal(t, offset, l.Offset())
+
+	platform.TriggerIntr(1)
+	waitEvent(t, ich, gpiod.LineEventRisingEdge)
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	platform.TriggerIntr(0)
+	waitEvent(t, ich, gpiod.LineEventFallingEdge)
+}
@@ -3,6 +3,7 @@ module github.com/warthog618/gpiod
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/pilebones/go-udev v0.0.0-20180820235104-043677e09b13
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/pflag v1.0.5 // indirect
@@ -10,4 +11,5 @@ require (
 	github.com/warthog618/config v0.4.1
 	golang.org/x/sys v0.0.0-20200223170610-d5e6a3e2c0ae
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
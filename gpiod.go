@@ -44,6 +44,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -61,6 +62,20 @@ type Chip struct {
 	// A more individual label for the chip.
 	Label string
 
+	// The sysfs path of the device that provides the chip.
+	//
+	// e.g. /sys/devices/platform/soc/3f200000.gpio
+	//
+	// This is empty if it cannot be determined.
+	Parent string
+
+	// The device tree node of the chip.
+	//
+	// e.g. /soc/gpio@7e200000
+	//
+	// This is empty if the chip is not described by device tree.
+	OfNode string
+
 	// The number of GPIO lines on this chip.
 	lines int
 
@@ -76,6 +91,24 @@ type Chip struct {
 	// handlers for info changes in watched lines, keyed by offset.
 	ich map[int]InfoChangeHandler
 
+	// subscriptions to info changes, in the order they were made.
+	subs []*InfoWatch
+
+	// the number of watches on each line, keyed by offset.
+	//
+	// A line is watched by the kernel while it has any watches.
+	watches map[int]int
+
+	// index of line names, built on first use.
+	ln *lineNames
+
+	// capabilities of the kernel and chip, probed on first use.
+	caps *Capabilities
+
+	// the sysfs backend, if the chip is accessed via sysfs rather than the
+	// character device.
+	sysfs *sysfsChip
+
 	// indicates the chip has been closed.
 	closed bool
 }
@@ -84,44 +117,69 @@ type Chip struct {
 // line.
 type LineInfo struct {
 	// The line offset within the chip.
-	Offset int
+	Offset int `json:"offset"`
 
 	// The system name for the line.
-	Name string
+	Name string `json:"name,omitempty"`
 
 	// A string identifying the requester of the line, if requested.
-	Consumer string
+	Consumer string `json:"consumer,omitempty"`
 
 	// True if the line is requested.
-	Requested bool
+	Requested bool `json:"requested,omitempty"`
 
 	// True if the line was requested as an output.
-	IsOut bool
+	IsOut bool `json:"is_out,omitempty"`
 
 	// True if the line was requested as active low.
-	ActiveLow bool
+	ActiveLow bool `json:"active_low,omitempty"`
 
 	// True if the line was requested as open drain.
 	//
 	// Only valid for outputs.
-	OpenDrain bool
+	OpenDrain bool `json:"open_drain,omitempty"`
 
 	// True if the line was requested as open source.
 	//
 	// Only valid for outputs.
-	OpenSource bool
+	OpenSource bool `json:"open_source,omitempty"`
 
 	// True if the line was requested with bias disabled.
-	BiasDisable bool
+	BiasDisable bool `json:"bias_disable,omitempty"`
 
 	// True if the line was requested with pull-down.
-	PullDown bool
+	PullDown bool `json:"pull_down,omitempty"`
 
 	// True if the line was requested with pull-up.
-	PullUp bool
+	PullUp bool `json:"pull_up,omitempty"`
+
+	// True if the line was requested with rising edge detection.
+	//
+	// Requires Linux v5.10 or later.
+	EdgeRising bool `json:"edge_rising,omitempty"`
+
+	// True if the line was requested with falling edge detection.
+	//
+	// Requires Linux v5.10 or later.
+	EdgeFalling bool `json:"edge_falling,omitempty"`
+
+	// The debounce period of the line, or zero if the line is not debounced.
+	//
+	// Requires Linux v5.10 or later.
+	DebouncePeriod time.Duration `json:"debounce_period,omitempty"`
+
+	// The clock used to timestamp edge events on the line.
+	//
+	// Requires Linux v5.10 or later, else the clock is reported as
+	// EventClockMonotonic.  EventClockRealtime requires Linux v5.11 or later,
+	// and EventClockHTE requires Linux v5.19 or later.
+	EventClock EventClock `json:"event_clock,omitempty"`
 }
 
 // Chips returns the names of the available GPIO devices.
+//
+// If no GPIO character devices are available then the names of the chips
+// available via the legacy sysfs GPIO interface are returned.
 func Chips() []string {
 	cc := []string(nil)
 	for _, name := range chipNames() {
@@ -129,34 +187,49 @@ func Chips() []string {
 			cc = append(cc, name)
 		}
 	}
+	if len(cc) == 0 {
+		cc = sysfsChipNames()
+	}
 	return cc
 }
 
 // FindLine finds the chip and offset of the named line.
 //
 // Returns an error if the line cannot be found.
+//
+// The line names for each chip are indexed on first use, so subsequent
+// lookups do not need to query the chips.
 func FindLine(lname string) (string, int, error) {
-	c, o, err := findLine(lname)
-	if err != nil {
-		return "", 0, err
+	for _, cn := range index.chips() {
+		if o, ok := cn.ln.find(lname); ok {
+			return cn.chip, o, nil
+		}
 	}
-	c.Close()
-	return c.Name, o, nil
+	return "", 0, ErrLineNotFound
 }
 
 // NewChip opens a GPIO character device.
+//
+// If the character device is not available, but the chip is available via
+// the legacy sysfs GPIO interface, then the chip is accessed via sysfs.  The
+// sysfs interface can also be selected explicitly by providing the path of
+// the chip in sysfs, e.g. /sys/class/gpio/gpiochip0.  Refer to
+// ErrorSysfsUnsupported for the limitations of sysfs.
 func NewChip(name string, options ...ChipOption) (*Chip, error) {
-	path := nameToPath(name)
-	err := IsChip(path)
-	if err != nil {
-		return nil, err
-	}
 	co := ChipOptions{
 		consumer: fmt.Sprintf("gpiod-%d", os.Getpid()),
 	}
 	for _, option := range options {
 		option.applyChipOption(&co)
 	}
+	path := nameToPath(name)
+	err := IsChip(path)
+	if err != nil {
+		if dir, ok := sysfsChipDir(name); ok {
+			return newSysfsChip(dir, co)
+		}
+		return nil, err
+	}
 	f, err := os.OpenFile(path, unix.O_CLOEXEC, unix.O_RDONLY)
 	if err != nil {
 		// only happens if device removed/locked since IsChip call.
@@ -178,9 +251,56 @@ func NewChip(name string, options ...ChipOption) (*Chip, error) {
 	if len(c.Label) == 0 {
 		c.Label = "unknown"
 	}
+	c.Parent = chipParent(c.Name)
+	c.OfNode = chipOfNode(c.Name)
 	return &c, nil
 }
 
+// FindChipByLabel finds the name of the chip with the given label.
+//
+// Returns an error if the chip cannot be found.
+func FindChipByLabel(label string) (string, error) {
+	return findChip(func(c *Chip) bool {
+		return c.Label == label
+	})
+}
+
+// FindChipByParent finds the name of the chip provided by the given device.
+//
+// The device may be identified by its full sysfs path, e.g.
+// /sys/devices/platform/soc/3f200000.gpio, or just by the device name, e.g.
+// 3f200000.gpio.
+//
+// Returns an error if the chip cannot be found.
+func FindChipByParent(parent string) (string, error) {
+	if strings.ContainsRune(parent, '/') {
+		if p, err := filepath.EvalSymlinks(parent); err == nil {
+			parent = p
+		}
+	}
+	return findChip(func(c *Chip) bool {
+		if len(c.Parent) == 0 {
+			return false
+		}
+		return c.Parent == parent || filepath.Base(c.Parent) == parent
+	})
+}
+
+// FindChipByOfNode finds the name of the chip described by the given device
+// tree node.
+//
+// The node may be provided as a device tree path, e.g. /soc/gpio@7e200000, or
+// as the corresponding path under /proc/device-tree or
+// /sys/firmware/devicetree/base.
+//
+// Returns an error if the chip cannot be found.
+func FindChipByOfNode(node string) (string, error) {
+	node = trimOfNode(node)
+	return findChip(func(c *Chip) bool {
+		return len(c.OfNode) != 0 && c.OfNode == node
+	})
+}
+
 // Close releases the Chip.
 //
 // It does not release any lines which may be requested - they must be closed
@@ -196,19 +316,25 @@ func (c *Chip) Close() error {
 	if c.iw != nil {
 		c.iw.close()
 	}
+	if c.f == nil {
+		return nil
+	}
 	return c.f.Close()
 }
 
 // FindLine returns the offset of the named line, or an error if not found.
+//
+// If more than one line has the name then the lowest offset is returned.
+//
+// The line names are read from the chip on first use and cached, refer to
+// InvalidateLineNames.
 func (c *Chip) FindLine(name string) (int, error) {
-	for o := 0; o < c.lines; o++ {
-		inf, err := c.LineInfo(o)
-		if err != nil {
-			return 0, err
-		}
-		if inf.Name == name {
-			return o, nil
-		}
+	ln, err := c.lineNames()
+	if err != nil {
+		return 0, err
+	}
+	if o, ok := ln.find(name); ok {
+		return o, nil
 	}
 	return 0, ErrLineNotFound
 }
@@ -216,11 +342,15 @@ func (c *Chip) FindLine(name string) (int, error) {
 // FindLines returns the offsets of the named lines, or an error unless all are
 // found.
 func (c *Chip) FindLines(names ...string) (oo []int, err error) {
+	ln, err := c.lineNames()
+	if err != nil {
+		return
+	}
 	ioo := make([]int, len(names))
 	for i, name := range names {
-		var o int
-		o, err = c.FindLine(name)
-		if err != nil {
+		o, ok := ln.find(name)
+		if !ok {
+			err = ErrLineNotFound
 			return
 		}
 		ioo[i] = o
@@ -243,12 +373,54 @@ func (c *Chip) LineInfo(offset int) (info LineInfo, err error) {
 		err = ErrInvalidOffset
 		return
 	}
-	var li uapi.LineInfo
-	li, err = uapi.GetLineInfo(c.f.Fd(), offset)
-	if err == nil {
-		info = newLineInfo(li)
+	if c.sysfs != nil {
+		return c.sysfs.lineInfo(offset)
 	}
-	return
+	return readLineInfo(c.f.Fd(), offset)
+}
+
+// readLineInfo reads the info for a line from the chip.
+func readLineInfo(fd uintptr, offset int) (LineInfo, error) {
+	li, err := uapi.GetLineInfo(fd, offset)
+	if err != nil {
+		return LineInfo{}, err
+	}
+	info := newLineInfo(li)
+	addLineInfoV2(fd, &info)
+	return info, nil
+}
+
+// addLineInfoV2 adds the attributes of a line only reported by the v2 uAPI
+// to the info, if the v2 uAPI is supported by the kernel.
+func addLineInfoV2(fd uintptr, info *LineInfo) {
+	li, err := uapi.GetLineInfoV2(fd, info.Offset)
+	if err != nil {
+		return
+	}
+	info.EdgeRising = li.Flags.IsEdgeRising()
+	info.EdgeFalling = li.Flags.IsEdgeFalling()
+	info.DebouncePeriod = li.DebouncePeriod()
+	switch {
+	case li.Flags.IsEventClockRealtime():
+		info.EventClock = EventClockRealtime
+	case li.Flags.IsEventClockHTE():
+		info.EventClock = EventClockHTE
+	default:
+		info.EventClock = EventClockMonotonic
+	}
+}
+
+// linesInfo returns the info for a set of lines.
+func (c *Chip) linesInfo(offsets []int) ([]*LineInfo, error) {
+	info := make([]*LineInfo, len(offsets))
+	for i, o := range offsets {
+		li, err := c.LineInfo(o)
+		if err != nil {
+			return nil, err
+		}
+		info[i] = &li
+	}
+	return info, nil
 }
 
 func newLineInfo(li uapi.LineInfo) LineInfo {
@@ -276,48 +448,137 @@ func (c *Chip) Lines() int {
 //
 // If granted, control is maintained until either the Line or Chip are closed.
 func (c *Chip) RequestLine(offset int, options ...LineOption) (*Line, error) {
-	ll, err := c.RequestLines([]int{offset}, options...)
+	ll, err := c.request([]int{offset}, options)
 	if err != nil {
 		return nil, err
 	}
+	l := newLine(ll)
+	policies.track(&l.baseLine)
+	return l, nil
+}
+
+// newLine converts a collection of one line into a Line.
+func newLine(ll *Lines) *Line {
 	l := Line{baseLine{
 		offsets:      ll.offsets,
 		vfd:          ll.vfd,
 		isEvent:      ll.isEvent,
-		chip:         c.Name,
+		chip:         ll.chip,
+		origin:       ll.origin,
+		infoWatch:    ll.infoWatch,
 		flags:        ll.flags,
 		outputValues: ll.outputValues,
+		info:         ll.info,
 		w:            ll.w,
+		closePolicy:  ll.closePolicy,
+		verify:       ll.verify,
+		emulate:      ll.emulate,
+		emulated:     ll.emulated,
+		split:        ll.split,
+		sysfs:        ll.sysfs,
 	}}
-	return &l, nil
+	if l.infoWatch != nil {
+		l.infoWatch.replaceHandler(l.updateInfo)
+	}
+	return &l
+}
+
+// LineOptions returns the effective options for lines requested from the
+// chip with the provided options.
+//
+// The effective options combine the chip defaults with the provided options.
+func (c *Chip) LineOptions(options ...LineOption) LineOptions {
+	lo := LineOptions{
+		consumer:    c.options.consumer,
+		HandleFlags: c.options.HandleFlags,
+	}
+	for _, option := range options {
+		option.applyLineOption(&lo)
+	}
+	return lo
 }
 
 // RequestLines requests control of a collection of lines on the chip.
+//
+// The options are validated before the lines are requested, and
+// contradictory or unsupported combinations of options are rejected.
+//
+// If any of the lines are in use then the request fails with unix.EBUSY,
+// unless the WithWait or WithWaitTimeout options are provided.
 func (c *Chip) RequestLines(offsets []int, options ...LineOption) (*Lines, error) {
+	ll, err := c.request(offsets, options)
+	if err != nil {
+		return nil, err
+	}
+	policies.track(&ll.baseLine)
+	return ll, nil
+}
+
+// request requests the lines, but does not track their close policy, so the
+// caller must call policies.track once the lines are in their final form.
+func (c *Chip) request(offsets []int, options []LineOption) (*Lines, error) {
 	for _, o := range offsets {
 		if o < 0 || o >= c.lines {
 			return nil, ErrInvalidOffset
 		}
 	}
-	lo := LineOptions{
-		consumer:    c.options.consumer,
-		HandleFlags: c.options.HandleFlags,
+	lo := c.LineOptions(options...)
+	if err := lo.Validate(len(offsets)); err != nil {
+		return nil, err
 	}
-	for _, option := range options {
-		option.applyLineOption(&lo)
+	if c.sysfs != nil {
+		if err := checkSysfs(lo); err != nil {
+			return nil, err
+		}
+	}
+	var ll *Lines
+	var err error
+	if lo.wait != nil {
+		ll, err = c.requestLinesWait(offsets, lo)
+	} else {
+		ll, err = c.requestLines(offsets, lo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lo.liveInfo {
+		if err = ll.watchInfo(c); err != nil {
+			ll.Close()
+			return nil, err
+		}
+	}
+	ll.closePolicy = lo.closePolicy
+	return ll, nil
+}
+
+func (c *Chip) requestLines(offsets []int, lo LineOptions) (*Lines, error) {
+	if c.sysfs != nil {
+		return c.requestSysfsLines(offsets, lo)
 	}
 	ll := Lines{baseLine{
 		offsets:      append([]int(nil), offsets...),
 		chip:         c.Name,
+		origin:       c,
 		flags:        lo.HandleFlags,
 		outputValues: lo.InitialValues,
+		verify:       lo.verify,
+		emulate:      lo.emulate,
 	}}
 	var err error
-	if lo.eh != nil {
+	switch {
+	case lo.eh != nil && lo.poll != nil:
+		ll.isEvent = true
+		ll.vfd, ll.w, err = c.getPolledRequest(ll.offsets, lo)
+	case lo.eh != nil:
 		ll.isEvent = true
 		ll.vfd, ll.w, err = c.getEventRequest(ll.offsets, lo)
-	} else {
+	case canEmulate(lo) && isForced(lo):
+		err = ll.requestEmulated(c, lo)
+	default:
 		ll.vfd, err = c.getHandleRequest(ll.offsets, lo)
+		if canEmulate(lo) && isDriveUnsupported(err) {
+			err = ll.requestEmulated(c, lo)
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -325,7 +586,7 @@ func (c *Chip) RequestLines(offsets []int, options ...LineOption) (*Lines, error
 	return &ll, nil
 }
 
-// creates the iw and ich
+// creates the iw, ich and watches
 //
 // Assumes c is locked.
 func (c *Chip) createInfoWatcher() error {
@@ -333,24 +594,77 @@ func (c *Chip) createInfoWatcher() error {
 		func(lic LineInfoChangeEvent) {
 			c.mu.Lock()
 			ich := c.ich[lic.Info.Offset]
-			c.mu.Unlock() // handler called outside lock
+			ehs := []InfoChangeHandler(nil)
+			for _, w := range c.subs {
+				if w.covers(lic.Info.Offset) {
+					ehs = append(ehs, w.eh)
+				}
+			}
+			if c.ln != nil {
+				c.ln = c.ln.rename(lic.Info.Offset, lic.Info.Name)
+			}
+			c.mu.Unlock() // handlers called outside lock
 			if ich != nil {
 				ich(lic)
 			}
+			for _, eh := range ehs {
+				eh(lic)
+			}
 		})
 	if err != nil {
 		return err
 	}
 	c.iw = iw
 	c.ich = map[int]InfoChangeHandler{}
+	c.watches = map[int]int{}
 	return nil
 }
 
+// watchLine adds a watch on the line, and returns the current info for the
+// line.
+//
+// The line is only watched by the kernel for the first watch, as the kernel
+// does not support nested watches.
+//
+// Assumes c is locked and the iw has been created.
+func (c *Chip) watchLine(offset int) (info LineInfo, err error) {
+	if c.watches[offset] > 0 {
+		info, err = readLineInfo(c.f.Fd(), offset)
+	} else {
+		li := uapi.LineInfo{Offset: uint32(offset)}
+		if err = uapi.WatchLineInfo(c.f.Fd(), &li); err == nil {
+			info = newLineInfo(li)
+			addLineInfoV2(c.f.Fd(), &info)
+		}
+	}
+	if err != nil {
+		return
+	}
+	c.watches[offset]++
+	return
+}
+
+// unwatchLine removes a watch on the line, and unwatches the line in the
+// kernel once it has no remaining watches.
+//
+// Assumes c is locked and the iw has been created.
+func (c *Chip) unwatchLine(offset int) error {
+	c.watches[offset]--
+	if c.watches[offset] > 0 {
+		return nil
+	}
+	delete(c.watches, offset)
+	return uapi.UnwatchLineInfo(c.f.Fd(), uint32(offset))
+}
+
 // WatchLineInfo enables watching changes to line info for the specified lines.
 //
 // The changes are reported via the chip InfoChangeHandler.
 // Repeated calls replace the InfoChangeHandler.
 //
+// Watches made by WatchLineInfo are independent of subscriptions made by
+// SubscribeLineInfo.
+//
 // Requires Linux v5.7 or later.
 func (c *Chip) WatchLineInfo(offset int, lich InfoChangeHandler) (info LineInfo, err error) {
 	c.mu.Lock()
@@ -359,19 +673,29 @@ func (c *Chip) WatchLineInfo(offset int, lich InfoChangeHandler) (info LineInfo,
 		err = ErrClosed
 		return
 	}
+	if c.sysfs != nil {
+		err = ErrorSysfsUnsupported{"watching line info"}
+		return
+	}
 	if c.iw == nil {
 		err = c.createInfoWatcher()
 		if err != nil {
 			return
 		}
 	}
-	li := uapi.LineInfo{Offset: uint32(offset)}
-	err = uapi.WatchLineInfo(c.f.Fd(), &li)
+	if _, ok := c.ich[offset]; ok {
+		info, err = readLineInfo(c.f.Fd(), offset)
+		if err != nil {
+			return
+		}
+		c.ich[offset] = lich
+		return
+	}
+	info, err = c.watchLine(offset)
 	if err != nil {
 		return
 	}
 	c.ich[offset] = lich
-	info = newLineInfo(li)
 	return
 }
 
@@ -381,11 +705,148 @@ func (c *Chip) WatchLineInfo(offset int, lich InfoChangeHandler) (info LineInfo,
 func (c *Chip) UnwatchLineInfo(offset int) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.closed {
+	if c.closed || c.sysfs != nil {
+		return nil
+	}
+	if _, ok := c.ich[offset]; !ok {
 		return nil
 	}
 	delete(c.ich, offset)
-	return uapi.UnwatchLineInfo(c.f.Fd(), uint32(offset))
+	return c.unwatchLine(offset)
+}
+
+// InfoWatch is a subscription to changes to the info of a set of lines on a
+// chip.
+//
+// Any number of subscriptions may include the same line.
+type InfoWatch struct {
+	c *Chip
+
+	// the watched lines, or nil for all lines on the chip.
+	offsets map[int]bool
+
+	// the handler for changes to the watched lines.
+	eh InfoChangeHandler
+
+	// set once the subscription is closed.
+	//
+	// Covered by the chip mutex.
+	closed bool
+}
+
+// replaceHandler replaces the handler for changes to the watched lines.
+func (w *InfoWatch) replaceHandler(eh InfoChangeHandler) {
+	w.c.mu.Lock()
+	w.eh = eh
+	w.c.mu.Unlock()
+}
+
+// SubscribeLineInfo subscribes to changes to the info of the specified lines,
+// or of all lines on the chip if no offsets are specified.
+//
+// Changes to all the lines are reported, in the order they occur, via the
+// InfoChangeHandler.  The handler is called from a goroutine shared by all
+// watches on the chip, so it should not block.  Changes can be forwarded to a
+// channel by a handler such as:
+//
+//  func(evt gpiod.LineInfoChangeEvent) { ch <- evt }
+//
+// Returns the current info of the subscribed lines, in the order of the
+// offsets.
+//
+// Subscriptions are independent of each other and of watches made by
+// WatchLineInfo, so a line may be included in any number of subscriptions.
+//
+// Requires Linux v5.7 or later.
+func (c *Chip) SubscribeLineInfo(eh InfoChangeHandler, offsets ...int) (*InfoWatch, []LineInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, nil, ErrClosed
+	}
+	if c.sysfs != nil {
+		return nil, nil, ErrorSysfsUnsupported{"watching line info"}
+	}
+	w := InfoWatch{c: c, eh: eh}
+	if len(offsets) == 0 {
+		offsets = make([]int, c.lines)
+		for o := range offsets {
+			offsets[o] = o
+		}
+	} else {
+		w.offsets = map[int]bool{}
+		for _, o := range offsets {
+			if o < 0 || o >= c.lines {
+				return nil, nil, ErrInvalidOffset
+			}
+			w.offsets[o] = true
+		}
+	}
+	if c.iw == nil {
+		err := c.createInfoWatcher()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	// each line is only watched once, even if repeated in offsets.
+	watched := map[int]LineInfo{}
+	for _, o := range offsets {
+		if _, ok := watched[o]; ok {
+			continue
+		}
+		li, err := c.watchLine(o)
+		if err != nil {
+			for o := range watched {
+				c.unwatchLine(o)
+			}
+			return nil, nil, err
+		}
+		watched[o] = li
+	}
+	info := make([]LineInfo, len(offsets))
+	for i, o := range offsets {
+		info[i] = watched[o]
+	}
+	c.subs = append(c.subs, &w)
+	return &w, info, nil
+}
+
+// Close ends the subscription.
+//
+// A change being reported when the subscription is closed may still be passed
+// to the handler after Close returns.
+func (w *InfoWatch) Close() error {
+	c := w.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	for i, s := range c.subs {
+		if s == w {
+			c.subs = append(c.subs[:i], c.subs[i+1:]...)
+			break
+		}
+	}
+	if c.closed {
+		// the chip has released all the watches.
+		return nil
+	}
+	var err error
+	for o := 0; o < c.lines; o++ {
+		if w.covers(o) {
+			if uerr := c.unwatchLine(o); uerr != nil && err == nil {
+				err = uerr
+			}
+		}
+	}
+	return err
+}
+
+// covers returns true if the subscription includes the line.
+func (w *InfoWatch) covers(offset int) bool {
+	return w.offsets == nil || w.offsets[offset]
 }
 
 func (c *Chip) getEventRequest(offsets []int, lo LineOptions) (uintptr, *watcher, error) {
@@ -400,6 +861,9 @@ func (c *Chip) getEventRequest(offsets []int, lo LineOptions) (uintptr, *watcher
 		copy(er.Consumer[:len(er.Consumer)-1], lo.consumer)
 		err := uapi.GetLineEvent(c.f.Fd(), &er)
 		if err != nil {
+			for fd := range fds {
+				unix.Close(fd)
+			}
 			return 0, nil, err
 		}
 		fd := uintptr(er.Fd)
@@ -408,7 +872,7 @@ func (c *Chip) getEventRequest(offsets []int, lo LineOptions) (uintptr, *watcher
 		}
 		fds[int(fd)] = o
 	}
-	w, err := newWatcher(fds, lo.eh)
+	w, err := newWatcher(c.Name, fds, lo.eh)
 	if err != nil {
 		for fd := range fds {
 			unix.Close(fd)
@@ -444,13 +908,31 @@ type baseLine struct {
 	vfd     uintptr
 	isEvent bool
 	chip    string
+	// the chip from which the lines were requested, if known, which is used
+	// to read the line info while it remains open.
+	origin *Chip
+	// the subscription keeping the info current, if WithLiveInfo.
+	infoWatch *InfoWatch
+	// configuration applied by Close, if any.
+	closePolicy []LineConfig
+	// verification of writes, if any.
+	verify *VerifyOption
+	// emulation of drive, if other than the default.
+	emulate *EmulationOption
+	// the handle requests for the individual lines, if the lines were split
+	// to emulate the drive.
+	split []uintptr
+	// the sysfs backend, if the lines were exported via sysfs.
+	sysfs *sysfsLines
 	// mu covers all that follow - those above are immutable
 	mu           sync.Mutex
 	flags        uapi.HandleFlag
 	outputValues []int
 	info         []*LineInfo
-	closed       bool
-	w            *watcher
+	// the emulated drive flag, if the drive is being emulated.
+	emulated uapi.HandleFlag
+	closed   bool
+	w        *watcher
 }
 
 // Chip returns the name of the chip from which the line was requested.
@@ -459,6 +941,10 @@ func (l *baseLine) Chip() string {
 }
 
 // Close releases all resources held by the requested line.
+//
+// If the line was requested with a close policy then the policy is applied
+// before the line is released.  Any error applying the policy is returned,
+// but the line is released regardless.
 func (l *baseLine) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -466,10 +952,151 @@ func (l *baseLine) Close() error {
 		return ErrClosed
 	}
 	l.closed = true
-	if l.w != nil {
+	var err error
+	if l.closePolicy != nil {
+		err = l.applyClosePolicy()
+		policies.remove(l)
+	}
+	if l.infoWatch != nil {
+		l.infoWatch.Close()
+	}
+	switch {
+	case l.w != nil:
 		l.w.close()
+	case l.sysfs != nil:
+		l.sysfs.closeFds()
+	default:
+		l.closeHandles()
+	}
+	if l.sysfs != nil {
+		l.sysfs.unexport()
+	}
+	return err
+}
+
+// getInfo returns the info for the lines, reading it if it is not cached.
+//
+// Assumes l is locked.
+func (l *baseLine) getInfo() ([]*LineInfo, error) {
+	if l.info != nil {
+		return l.info, nil
+	}
+	var info []*LineInfo
+	var err error
+	if l.sysfs != nil {
+		info, err = l.sysfs.info()
+	} else {
+		info, err = l.readInfo()
+	}
+	if err == nil {
+		l.info = info
+	}
+	return info, err
+}
+
+// readInfo reads the info for the lines from the chip from which they were
+// requested or, if that has been closed, from a newly opened chip.
+func (l *baseLine) readInfo() ([]*LineInfo, error) {
+	if l.origin != nil {
+		info, err := l.origin.linesInfo(l.offsets)
+		if err != ErrClosed {
+			return info, err
+		}
+	}
+	c, err := NewChip(l.chip)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.linesInfo(l.offsets)
+}
+
+// watchInfo keeps the info for the lines current by subscribing to changes
+// to the info.
+func (l *baseLine) watchInfo(c *Chip) error {
+	w, info, err := c.SubscribeLineInfo(l.updateInfo, l.offsets...)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.infoWatch = w
+	l.info = make([]*LineInfo, len(info))
+	for i := range info {
+		l.info[i] = &info[i]
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// updateInfo updates the cached info for a line from an info change event.
+func (l *baseLine) updateInfo(evt LineInfoChangeEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.info == nil {
+		// reread on demand.
+		return
+	}
+	// copy, as the previous info may have been returned by Info.
+	info := append([]*LineInfo(nil), l.info...)
+	for i, o := range l.offsets {
+		if o == evt.Info.Offset {
+			li := evt.Info
+			info[i] = &li
+		}
+	}
+	l.info = info
+}
+
+// getValues reads the values of the lines.
+//
+// Assumes l is locked.
+func (l *baseLine) getValues(values *uapi.HandleData) error {
+	if l.sysfs != nil {
+		return l.sysfs.values(values)
+	}
+	return l.readValues(values)
+}
+
+// setValues sets the values of the lines, and verifies the values if
+// required.
+//
+// The values are recorded as the output values once written, as writing
+// lines with emulated drive depends on the previous values.
+//
+// Assumes l is locked.
+func (l *baseLine) setValues(vv []int) error {
+	var values uapi.HandleData
+	for i, v := range vv {
+		values[i] = uint8(v)
+	}
+	var err error
+	if l.sysfs != nil {
+		err = l.sysfs.setValues(values)
 	} else {
-		unix.Close(int(l.vfd))
+		err = l.writeValues(values)
+	}
+	if err == nil {
+		l.outputValues = vv
+	}
+	if err != nil || l.verify == nil {
+		return err
+	}
+	if l.verify.settle > 0 {
+		time.Sleep(l.verify.settle)
+	}
+	var rvv uapi.HandleData
+	err = l.getValues(&rvv)
+	if err != nil {
+		return err
+	}
+	oo := []int(nil)
+	for i, o := range l.offsets {
+		if (values[i] == 0) != (rvv[i] == 0) {
+			oo = append(oo, o)
+		}
+	}
+	if oo != nil {
+		return ErrorMismatch{oo}
 	}
 	return nil
 }
@@ -477,6 +1104,8 @@ func (l *baseLine) Close() error {
 // Reconfigure updates the configuration of the requested line(s).
 //
 // Configuration for options other than those passed in remain unchanged.
+// The resulting configuration is validated, as per LineOptions.Validate,
+// before being applied.
 //
 // Not valid for lines with edge detection enabled.
 //
@@ -496,17 +1125,41 @@ func (l *baseLine) Reconfigure(options ...LineConfig) error {
 	lo := LineOptions{
 		HandleFlags:   l.flags,
 		InitialValues: l.outputValues,
+		emulate:       l.emulate,
 	}
 	for _, option := range options {
 		option.applyLineConfig(&lo)
 	}
-	hc := uapi.HandleConfig{Flags: lo.HandleFlags}
-	for i, v := range lo.InitialValues {
-		hc.DefaultValues[i] = uint8(v)
+	if err := lo.Validate(len(l.offsets)); err != nil {
+		return err
+	}
+	err := l.reconfigure(lo)
+	if err == nil {
+		// reread on demand to reflect the new configuration.
+		l.info = nil
+	}
+	return err
+}
+
+// reconfigure applies the configuration to the lines.
+//
+// Assumes l is locked.
+func (l *baseLine) reconfigure(lo LineOptions) error {
+	if l.sysfs != nil {
+		return l.reconfigureSysfs(lo)
+	}
+	emulate := canEmulate(lo)
+	if emulate && (l.emulated != 0 || isForced(lo)) {
+		return l.reconfigureEmulated(lo)
+	}
+	err := l.writeConfig(lo.HandleFlags, lo.InitialValues)
+	if emulate && isDriveUnsupported(err) {
+		return l.reconfigureEmulated(lo)
 	}
-	err := uapi.SetLineConfig(l.vfd, &hc)
 	if err == nil {
-		l.flags = hc.Flags
+		l.flags = lo.HandleFlags
+		l.outputValues = lo.InitialValues
+		l.emulated = 0
 	}
 	return err
 }
@@ -522,6 +1175,10 @@ func (l *Line) Offset() int {
 }
 
 // Info returns the information about the line.
+//
+// The info is read from the chip on first use, and cached until the line is
+// reconfigured.  Lines requested with WithLiveInfo are updated as the info
+// changes.
 func (l *Line) Info() (info LineInfo, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -529,21 +1186,10 @@ func (l *Line) Info() (info LineInfo, err error) {
 		err = ErrClosed
 		return
 	}
-	if l.info != nil {
-		info = *l.info[0]
-		return
-	}
-	c, err := NewChip(l.chip)
-	if err != nil {
-		return
+	var ii []*LineInfo
+	if ii, err = l.getInfo(); err == nil {
+		info = *ii[0]
 	}
-	defer c.Close()
-	inf, err := c.LineInfo(l.offsets[0])
-	if err != nil {
-		return
-	}
-	l.info = []*LineInfo{&inf}
-	info = *l.info[0]
 	return
 }
 
@@ -555,7 +1201,7 @@ func (l *Line) Value() (int, error) {
 		return 0, ErrClosed
 	}
 	var values uapi.HandleData
-	err := uapi.GetLineValues(l.vfd, &values)
+	err := l.getValues(&values)
 	return int(values[0]), err
 }
 
@@ -571,10 +1217,7 @@ func (l *Line) SetValue(value int) error {
 	if l.closed {
 		return ErrClosed
 	}
-	l.outputValues = []int{value}
-	var values uapi.HandleData
-	values[0] = uint8(value)
-	return uapi.SetLineValues(l.vfd, values)
+	return l.setValues([]int{value})
 }
 
 // Lines represents a collection of requested lines.
@@ -588,30 +1231,17 @@ func (l *Lines) Offsets() []int {
 }
 
 // Info returns the information about the lines.
+//
+// The info is read from the chip on first use, and cached until the lines are
+// reconfigured.  Lines requested with WithLiveInfo are updated as the info
+// changes.
 func (l *Lines) Info() ([]*LineInfo, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	if l.closed {
 		return nil, ErrClosed
 	}
-	if l.info != nil {
-		return l.info, nil
-	}
-	c, err := NewChip(l.chip)
-	if err != nil {
-		return nil, err
-	}
-	defer c.Close()
-	info := make([]*LineInfo, len(l.offsets))
-	for i, o := range l.offsets {
-		inf, err := c.LineInfo(o)
-		if err != nil {
-			return nil, err
-		}
-		info[i] = &inf
-	}
-	l.info = info
-	return l.info, nil
+	return l.getInfo()
 }
 
 // Values returns the current values (active state) of the collection of lines.
@@ -625,7 +1255,7 @@ func (l *Lines) Values(values []int) error {
 		return ErrClosed
 	}
 	var uvv uapi.HandleData
-	err := uapi.GetLineValues(l.vfd, &uvv)
+	err := l.getValues(&uvv)
 	if err != nil {
 		return err
 	}
@@ -657,12 +1287,7 @@ func (l *Lines) SetValues(values []int) error {
 	if l.closed {
 		return ErrClosed
 	}
-	l.outputValues = append([]int(nil), values...)
-	var vv uapi.HandleData
-	for i, v := range values {
-		vv[i] = uint8(v)
-	}
-	return uapi.SetLineValues(l.vfd, vv)
+	return l.setValues(append([]int(nil), values...))
 }
 
 // LineEventType indicates the type of change to the line active state.
@@ -682,9 +1307,18 @@ const (
 
 // LineEvent represents a change in the state of a line.
 type LineEvent struct {
+	// The name of the GPIO chip containing the line.
+	Chip string
+
 	// The line offset within the GPIO chip.
 	Offset int
 
+	// The name by which the line was requested.
+	//
+	// This is only set for lines requested by name, such as via
+	// RequestLinesByName or Chip.RequestNamedLines.
+	Name string
+
 	// Timestamp indicates the time the event was detected.
 	//
 	// The timestamp is intended for accurately measuring intervals between
@@ -700,6 +1334,10 @@ type LineEvent struct {
 // LineInfoChangeEvent represents a change in the info a line.
 type LineInfoChangeEvent struct {
 	// Info is the updated line info.
+	//
+	// The kernel only reports the v1 uAPI attributes in the event, so the
+	// edge detection, debounce period and event clock are read from the chip
+	// when the event is read, and may reflect subsequent changes.
 	Info LineInfo
 
 	// Timestamp indicates the time the event was detected.
@@ -789,21 +1427,91 @@ func chipNames() []string {
 	return cc
 }
 
-// helper that finds the chip and offset corresponding to a named line.
-//
-// If found returns the chip and offset, else an error.
-func findLine(lname string) (*Chip, int, error) {
+// helper that finds the name of the first chip satisfying the match function.
+func findChip(match func(*Chip) bool) (string, error) {
 	for _, name := range chipNames() {
 		c, err := NewChip(name)
 		if err != nil {
 			continue
 		}
-		o, err := c.FindLine(lname)
+		c.Close()
+		if match(c) {
+			return c.Name, nil
+		}
+	}
+	return "", ErrChipNotFound
+}
+
+// chipParent returns the sysfs path of the device providing the named chip.
+func chipParent(name string) string {
+	p, err := filepath.EvalSymlinks(sysfsChipPath(name))
+	if err != nil {
+		return ""
+	}
+	return filepath.Dir(p)
+}
+
+// chipOfNode returns the device tree node of the named chip.
+//
+// The of_node is checked on both the chip and its parent device, as older
+// kernels only provide it on the parent.
+func chipOfNode(name string) string {
+	sp := sysfsChipPath(name)
+	for _, p := range []string{sp + "/of_node", sp + "/device/of_node"} {
+		n, err := filepath.EvalSymlinks(p)
 		if err == nil {
-			return c, o, nil
+			return trimOfNode(n)
+		}
+	}
+	return ""
+}
+
+// trimOfNode converts the path of a device tree node to its device tree
+// path.
+func trimOfNode(node string) string {
+	for _, prefix := range []string{"/proc/device-tree", devicetreePath} {
+		if strings.HasPrefix(node, prefix) {
+			node = node[len(prefix):]
+			break
 		}
 	}
-	return nil, 0, ErrLineNotFound
+	if !strings.HasPrefix(node, "/") {
+		node = "/" + node
+	}
+	return node
+}
+
+func sysfsChipPath(name string) string {
+	return "/sys/bus/gpio/devices/" + name
+}
+
+// the sysfs location of the device tree.
+const devicetreePath = "/sys/firmware/devicetree/base"
+
+// ErrorMismatch indicates that the values read back from output lines do not
+// match the values written to them, as may occur if a line is shorted or
+// stuck.
+type ErrorMismatch struct {
+	// The offsets of the lines that do not match.
+	Offsets []int
+}
+
+func (e ErrorMismatch) Error() string {
+	return fmt.Sprintf("readback mismatch on offsets %v", e.Offsets)
+}
+
+// ErrorDuplicateLine indicates that two logical names map to the same line.
+type ErrorDuplicateLine struct {
+	// The logical names, in sorted order.
+	Names [2]string
+
+	// The offset of the line.
+	Offset int
+}
+
+func (e ErrorDuplicateLine) Error() string {
+	return fmt.Sprintf("lines '%s' and '%s' are both offset %d",
+		e.Names[0], e.Names[1], e.Offset)
 }
 
 func nameToPath(name string) string {
@@ -826,7 +1534,45 @@ var (
 	// ErrLineNotFound indicates the line was not found.
 	ErrLineNotFound = errors.New("line not found")
 
+	// ErrChipNotFound indicates the chip was not found.
+	ErrChipNotFound = errors.New("chip not found")
+
 	// ErrPermissionDenied indicates caller does not have required permissions
 	// for the operation.
 	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrTooManyValues indicates more values were provided than there are
+	// lines.
+	ErrTooManyValues = errors.New("more values than lines")
+
+	// ErrEdgeConflict indicates an edge detection option was overridden by a
+	// later output option.
+	ErrEdgeConflict = errors.New("edge detection conflicts with output")
+
+	// ErrDriveConflict indicates a drive option was overridden by a later
+	// option that clears the output direction.
+	ErrDriveConflict = errors.New("drive requires output direction")
+
+	// ErrBiasConflict indicates a bias option was provided for a line with
+	// neither input nor output direction.
+	ErrBiasConflict = errors.New("bias requires input or output direction")
+
+	// ErrClosePolicyConflict indicates a close policy was provided for lines
+	// with edge detection enabled.
+	ErrClosePolicyConflict = errors.New("close policy conflicts with edge detection")
+
+	// ErrEmulationConflict indicates the drive of lines requested together
+	// must be emulated, which requires the lines be requested individually.
+	ErrEmulationConflict = errors.New("drive emulation requires individually requested lines")
+
+	// ErrPollConflict indicates polling was requested without edge detection.
+	ErrPollConflict = errors.New("polling requires edge detection")
+
+	// ErrInvalidHandoff indicates the lines received from another process are
+	// not valid.
+	ErrInvalidHandoff = errors.New("invalid line handoff")
+
+	// ErrNotLineRequest indicates a file descriptor is not a line request
+	// matching the provided offsets.
+	ErrNotLineRequest = errors.New("not a line request")
 )
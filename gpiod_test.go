@@ -11,6 +11,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -40,12 +41,6 @@ func TestMain(m *testing.M) {
 	os.Exit(rc)
 }
 
-var (
-	biasKernel      = mockup.Semver{5, 5} // bias flags added
-	setConfigKernel = mockup.Semver{5, 5} // setLineConfig ioctl added
-	infoWatchKernel = mockup.Semver{5, 7} // watchLineInfo ioctl added
-)
-
 func TestNewChip(t *testing.T) {
 	// non-existent
 	c, err := gpiod.NewChip(platform.Devpath() + "not")
@@ -100,6 +95,54 @@ func TestFindLine(t *testing.T) {
 	assert.Equal(t, 0, len(cname))
 }
 
+func TestFindChipByLabel(t *testing.T) {
+	cname, err := gpiod.FindChipByLabel(platform.Label())
+	assert.Nil(t, err)
+	assert.Equal(t, platform.Name(), cname)
+
+	cname, err = gpiod.FindChipByLabel("nonexistent")
+	assert.Equal(t, gpiod.ErrChipNotFound, err)
+	assert.Equal(t, 0, len(cname))
+}
+
+func TestFindChipByParent(t *testing.T) {
+	c := getChip(t)
+	c.Close()
+	require.NotEmpty(t, c.Parent)
+
+	// full path
+	cname, err := gpiod.FindChipByParent(c.Parent)
+	assert.Nil(t, err)
+	assert.Equal(t, platform.Name(), cname)
+
+	// device name
+	cname, err = gpiod.FindChipByParent(filepath.Base(c.Parent))
+	assert.Nil(t, err)
+	assert.Equal(t, platform.Name(), cname)
+
+	cname, err = gpiod.FindChipByParent("nonexistent")
+	assert.Equal(t, gpiod.ErrChipNotFound, err)
+	assert.Equal(t, 0, len(cname))
+}
+
+func TestFindChipByOfNode(t *testing.T) {
+	c := getChip(t)
+	c.Close()
+	if len(c.OfNode) != 0 {
+		cname, err := gpiod.FindChipByOfNode(c.OfNode)
+		assert.Nil(t, err)
+		assert.Equal(t, platform.Name(), cname)
+
+		cname, err = gpiod.FindChipByOfNode("/proc/device-tree" + c.OfNode)
+		assert.Nil(t, err)
+		assert.Equal(t, platform.Name(), cname)
+	}
+
+	cname, err := gpiod.FindChipByOfNode("/nonexistent")
+	assert.Equal(t, gpiod.ErrChipNotFound, err)
+	assert.Equal(t, 0, len(cname))
+}
+
 func TestChipClose(t *testing.T) {
 	// without lines
 	c := getChip(t)
@@ -282,7 +325,7 @@ func TestChipRequestLines(t *testing.T) {
 }
 
 func TestChipWatchLineInfo(t *testing.T) {
-	requireKernel(t, infoWatchKernel)
+	requireCapability(t, gpiod.CapInfoWatch)
 
 	c := getChip(t)
 
@@ -333,7 +376,7 @@ func TestChipWatchLineInfo(t *testing.T) {
 }
 
 func TestChipUnwatchLineInfo(t *testing.T) {
-	requireKernel(t, infoWatchKernel)
+	requireCapability(t, gpiod.CapInfoWatch)
 
 	c := getChip(t)
 	c.Close()
@@ -369,6 +412,84 @@ func TestChipUnwatchLineInfo(t *testing.T) {
 	assert.Zero(t, wc)
 }
 
+func TestChipSubscribeLineInfo(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+
+	c := getChip(t)
+
+	lo := platform.FloatingLines()[0]
+	wc1 := make(chan gpiod.LineInfoChangeEvent, 5)
+	watcher1 := func(info gpiod.LineInfoChangeEvent) {
+		wc1 <- info
+	}
+
+	// closed
+	c.Close()
+	w1, info, err := c.SubscribeLineInfo(watcher1)
+	require.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, w1)
+	assert.Nil(t, info)
+
+	c = getChip(t)
+	defer c.Close()
+
+	// invalid offset
+	_, _, err = c.SubscribeLineInfo(watcher1, c.Lines())
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+
+	// all lines
+	w1, info, err = c.SubscribeLineInfo(watcher1)
+	require.Nil(t, err)
+	require.NotNil(t, w1)
+	require.Equal(t, c.Lines(), len(info))
+	for o, li := range info {
+		assert.Equal(t, o, li.Offset)
+	}
+
+	// same line in multiple subscriptions, and by WatchLineInfo
+	wc2 := make(chan gpiod.LineInfoChangeEvent, 5)
+	w2, info, err := c.SubscribeLineInfo(func(info gpiod.LineInfoChangeEvent) {
+		wc2 <- info
+	}, lo, lo)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(info))
+	assert.Equal(t, lo, info[0].Offset)
+	assert.Equal(t, lo, info[1].Offset)
+	wc3 := make(chan gpiod.LineInfoChangeEvent, 5)
+	_, err = c.WatchLineInfo(lo, func(info gpiod.LineInfoChangeEvent) {
+		wc3 <- info
+	})
+	require.Nil(t, err)
+
+	l, err := c.RequestLine(lo)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	waitInfoEvent(t, wc1, gpiod.LineRequested)
+	waitInfoEvent(t, wc2, gpiod.LineRequested)
+	waitInfoEvent(t, wc3, gpiod.LineRequested)
+	waitNoInfoEvent(t, wc2)
+
+	// closing one watch does not disturb the others
+	err = c.UnwatchLineInfo(lo)
+	assert.Nil(t, err)
+	err = w2.Close()
+	assert.Nil(t, err)
+	err = w2.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	l.Close()
+	waitInfoEvent(t, wc1, gpiod.LineReleased)
+	waitNoInfoEvent(t, wc2)
+	waitNoInfoEvent(t, wc3)
+
+	err = w1.Close()
+	assert.Nil(t, err)
+	l, err = c.RequestLine(lo)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	waitNoInfoEvent(t, wc1)
+	l.Close()
+}
+
 func TestLineChip(t *testing.T) {
 	c := getChip(t)
 	defer c.Close()
@@ -419,6 +540,86 @@ func TestLineInfo(t *testing.T) {
 	assert.Equal(t, gpiod.ErrClosed, err)
 }
 
+func TestLineInfoEdges(t *testing.T) {
+	requireCapability(t, gpiod.CapUapiV2)
+
+	c := getChip(t)
+	defer c.Close()
+	l, err := c.RequestLine(platform.IntrLine(),
+		gpiod.WithRisingEdge(func(gpiod.LineEvent) {}))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	li, err := l.Info()
+	assert.Nil(t, err)
+	assert.True(t, li.EdgeRising)
+	assert.False(t, li.EdgeFalling)
+	assert.Zero(t, li.DebouncePeriod)
+	assert.Equal(t, gpiod.EventClockMonotonic, li.EventClock)
+}
+
+func TestLineInfoReconfigure(t *testing.T) {
+	requireCapability(t, gpiod.CapSetConfig)
+
+	c := getChip(t)
+	l, err := c.RequestLine(platform.FloatingLines()[0], gpiod.AsInput)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	li, err := l.Info()
+	assert.Nil(t, err)
+	assert.False(t, li.ActiveLow)
+
+	// refreshed after reconfigure
+	err = l.Reconfigure(gpiod.AsActiveLow)
+	assert.Nil(t, err)
+	li, err = l.Info()
+	assert.Nil(t, err)
+	assert.True(t, li.ActiveLow)
+
+	// refreshed after the chip is closed
+	c.Close()
+	err = l.Reconfigure(gpiod.AsActiveHigh)
+	assert.Nil(t, err)
+	li, err = l.Info()
+	assert.Nil(t, err)
+	assert.False(t, li.ActiveLow)
+}
+
+func TestLineInfoLive(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+
+	c := getChip(t)
+	defer c.Close()
+	lo := platform.FloatingLines()[0]
+	l, err := c.RequestLine(lo, gpiod.AsInput, gpiod.WithLiveInfo)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	cli, err := c.LineInfo(lo)
+	assert.Nil(t, err)
+	li, err := l.Info()
+	assert.Nil(t, err)
+	assert.Equal(t, cli, li)
+
+	// the line is only watched while requested
+	wc := make(chan gpiod.LineInfoChangeEvent, 5)
+	_, err = c.WatchLineInfo(lo, func(info gpiod.LineInfoChangeEvent) {
+		wc <- info
+	})
+	require.Nil(t, err)
+	err = l.Reconfigure(gpiod.AsActiveLow)
+	assert.Nil(t, err)
+	waitInfoEvent(t, wc, gpiod.LineReconfigured)
+	li, err = l.Info()
+	assert.Nil(t, err)
+	assert.True(t, li.ActiveLow)
+	err = l.Close()
+	assert.Nil(t, err)
+	waitInfoEvent(t, wc, gpiod.LineReleased)
+	err = c.UnwatchLineInfo(lo)
+	assert.Nil(t, err)
+}
+
 func TestLineOffset(t *testing.T) {
 	c := getChip(t)
 	defer c.Close()
@@ -431,7 +632,7 @@ func TestLineOffset(t *testing.T) {
 }
 
 func TestLineReconfigure(t *testing.T) {
-	requireKernel(t, setConfigKernel)
+	requireCapability(t, gpiod.CapSetConfig)
 
 	c := getChip(t)
 	defer c.Close()
@@ -459,6 +660,30 @@ func TestLineReconfigure(t *testing.T) {
 	l.Close()
 }
 
+func TestLineReconfigureOutputValues(t *testing.T) {
+	requireCapability(t, gpiod.CapSetConfig)
+
+	c := getChip(t)
+	defer c.Close()
+
+	offsets := platform.FloatingLines()[:2]
+	ll, err := c.RequestLines(offsets, gpiod.AsOutput(0, 0))
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+	defer ll.Close()
+
+	err = ll.Reconfigure(gpiod.AsOutput(1, 1))
+	assert.Nil(t, err)
+
+	// later reconfigurations retain the values
+	err = ll.Reconfigure(gpiod.AsActiveLow)
+	assert.Nil(t, err)
+	vv := make([]int, len(offsets))
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 1}, vv)
+}
+
 func TestLineValue(t *testing.T) {
 	c := getChip(t)
 	defer c.Close()
@@ -884,7 +1109,8 @@ type Mockup struct {
 }
 
 func newMockup() (*Mockup, error) {
-	m, err := mockup.New([]int{20}, true)
+	// the second chip is only used by tests spanning multiple chips.
+	m, err := mockup.New([]int{20, 8}, true)
 	if err != nil {
 		return nil, err
 	}
@@ -940,8 +1166,13 @@ func newPlatform(pname string) (Platform, error) {
 	}
 }
 
-func requireKernel(t *testing.T, min mockup.Semver) {
-	if err := mockup.CheckKernelVersion(min); err != nil {
-		t.Skip(err)
+func requireCapability(t *testing.T, caps gpiod.Capabilities) {
+	t.Helper()
+	c := getChip(t)
+	defer c.Close()
+	have, err := c.Capabilities()
+	require.Nil(t, err)
+	if !have.Has(caps) {
+		t.Skipf("require %s, but only %s supported", caps&^have, have)
 	}
 }
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// handoff describes requested lines being transferred between processes.
+//
+// The file descriptors of the request are passed alongside, in offset order
+// for event requests and lines split to emulate the drive.
+type handoff struct {
+	Chip     string          `json:"chip"`
+	Offsets  []int           `json:"offsets"`
+	Flags    uapi.HandleFlag `json:"flags"`
+	Values   []int           `json:"values,omitempty"`
+	Event    bool            `json:"event,omitempty"`
+	Emulated uapi.HandleFlag `json:"emulated,omitempty"`
+	Split    bool            `json:"split,omitempty"`
+
+	// the polling interval and edges, if edges are detected by polling.
+	Poll  time.Duration  `json:"poll,omitempty"`
+	Edges uapi.EventFlag `json:"edges,omitempty"`
+}
+
+// Export transfers control of the requested lines to another process via the
+// Unix socket, without releasing the lines.
+//
+// The configuration of the lines is sent along with the file descriptors of
+// the request, and the lines can be reconstructed by the receiving process
+// using ImportLine or ImportLines.
+//
+// On success the lines are closed in this process, without applying any close
+// policy, and the lines remain requested by the receiving process.
+func (l *baseLine) Export(conn *net.UnixConn) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	if l.sysfs != nil {
+		return ErrorSysfsUnsupported{"handoff"}
+	}
+	h := handoff{
+		Chip:     l.chip,
+		Offsets:  l.offsets,
+		Flags:    l.flags,
+		Values:   l.outputValues,
+		Event:    l.isEvent,
+		Emulated: l.emulated,
+		Split:    l.split != nil,
+	}
+	fds := []int(nil)
+	for _, fd := range l.handles() {
+		fds = append(fds, int(fd))
+	}
+	switch {
+	case l.w != nil && l.w.poll != nil:
+		// the underlying request is a handle request.
+		h.Event = false
+		h.Poll = l.w.poll.interval
+		h.Edges = l.w.poll.edge
+	case l.w != nil:
+		fds = l.w.fdsByOffset(l.offsets)
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	_, _, err = conn.WriteMsgUnix(data, unix.UnixRights(fds...), nil)
+	if err != nil {
+		return err
+	}
+	l.closed = true
+	if l.closePolicy != nil {
+		policies.remove(l)
+	}
+	if l.infoWatch != nil {
+		l.infoWatch.Close()
+	}
+	if l.w != nil {
+		l.w.close()
+	} else {
+		l.closeHandles()
+	}
+	return nil
+}
+
+// ImportLine receives a requested line exported by another process via the
+// Unix socket, and reconstructs it.
+//
+// Refer to ImportLines for details.
+func ImportLine(conn *net.UnixConn, options ...LineOption) (*Line, error) {
+	ll, err := importLines(conn, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(ll.offsets) != 1 {
+		ll.Close()
+		return nil, ErrInvalidHandoff
+	}
+	l := newLine(ll)
+	policies.track(&l.baseLine)
+	return l, nil
+}
+
+// ImportLines receives requested lines exported by another process via the
+// Unix socket, and reconstructs them.
+//
+// The options may provide an event handler for lines with edge detection, as
+// well as the OnClose, WithVerifiedWrites and drive emulation options.
+// Options which would alter the configuration of the lines are ignored - use
+// Reconfigure for that.
+func ImportLines(conn *net.UnixConn, options ...LineOption) (*Lines, error) {
+	ll, err := importLines(conn, options)
+	if err != nil {
+		return nil, err
+	}
+	policies.track(&ll.baseLine)
+	return ll, nil
+}
+
+// importLines receives the lines, but does not track their close policy.
+func importLines(conn *net.UnixConn, options []LineOption) (*Lines, error) {
+	buf := make([]byte, 4096)
+	oob := make([]byte, unix.CmsgSpace(uapi.HandlesMax*4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, err
+	}
+	fds, err := parseRights(oob[:oobn])
+	if err != nil {
+		return nil, err
+	}
+	h := handoff{}
+	err = json.Unmarshal(buf[:n], &h)
+	if err == nil {
+		var ll *Lines
+		if ll, err = wrapLines(h, fds, options); err == nil {
+			return ll, nil
+		}
+	}
+	for _, fd := range fds {
+		unix.Close(fd)
+	}
+	return nil, err
+}
+
+func parseRights(oob []byte) ([]int, error) {
+	scms, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	fds := []int(nil)
+	for _, scm := range scms {
+		rights, err := unix.ParseUnixRights(&scm)
+		if err != nil {
+			return nil, err
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, nil
+}
+
+// wrapLines constructs Lines around the fds of an existing request.
+//
+// The fds are not closed on error.
+func wrapLines(h handoff, fds []int, options []LineOption) (*Lines, error) {
+	if len(h.Offsets) == 0 || len(fds) == 0 ||
+		((h.Event || h.Split) && len(fds) != len(h.Offsets)) ||
+		(!h.Event && !h.Split && len(fds) != 1) {
+		return nil, ErrInvalidHandoff
+	}
+	lo := LineOptions{}
+	for _, option := range options {
+		option.applyLineOption(&lo)
+	}
+	ll := Lines{baseLine{
+		offsets:      h.Offsets,
+		vfd:          uintptr(fds[0]),
+		isEvent:      h.Event,
+		chip:         h.Chip,
+		closePolicy:  lo.closePolicy,
+		verify:       lo.verify,
+		emulate:      lo.emulate,
+		flags:        h.Flags,
+		outputValues: h.Values,
+		emulated:     h.Emulated,
+	}}
+	if h.Split {
+		ll.split = make([]uintptr, len(fds))
+		for i, fd := range fds {
+			ll.split[i] = uintptr(fd)
+		}
+	}
+	eh := lo.eh
+	if eh == nil {
+		eh = func(LineEvent) {}
+	}
+	var err error
+	switch {
+	case h.Poll != 0:
+		ll.isEvent = true
+		p := newHandlePoller(ll.vfd, ll.offsets, h.Edges, h.Poll)
+		if ll.w, err = newPollWatcher(h.Chip, p, eh); err != nil {
+			return nil, err
+		}
+	case h.Event:
+		evtfds := make(map[int]int, len(fds))
+		for i, fd := range fds {
+			evtfds[fd] = h.Offsets[i]
+		}
+		if ll.w, err = newWatcher(h.Chip, evtfds, eh); err != nil {
+			return nil, err
+		}
+	}
+	return &ll, nil
+}
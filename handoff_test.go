@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"golang.org/x/sys/unix"
+)
+
+func unixPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	t.Helper()
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM, 0)
+	require.Nil(t, err)
+	conn := func(fd int) *net.UnixConn {
+		f := os.NewFile(uintptr(fd), "handoff")
+		defer f.Close()
+		c, err := net.FileConn(f)
+		require.Nil(t, err)
+		return c.(*net.UnixConn)
+	}
+	return conn(fds[0]), conn(fds[1])
+}
+
+func TestHandoffLine(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.OutLine()
+
+	tx, rx := unixPair(t)
+	defer tx.Close()
+	defer rx.Close()
+
+	l, err := c.RequestLine(offset, gpiod.AsOutput(1))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+
+	err = l.Export(tx)
+	require.Nil(t, err)
+
+	// exported line is closed locally
+	err = l.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	err = l.Export(tx)
+	assert.Equal(t, gpiod.ErrClosed, err)
+
+	il, err := gpiod.ImportLine(rx)
+	require.Nil(t, err)
+	require.NotNil(t, il)
+	assert.Equal(t, offset, il.Offset())
+	assert.Equal(t, c.Name, il.Chip())
+
+	// line was not released
+	inf, err := c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.True(t, inf.Requested)
+	assert.True(t, inf.IsOut)
+	assert.Equal(t, 1, platform.ReadOut())
+
+	err = il.SetValue(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, platform.ReadOut())
+	v, err := il.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+
+	err = il.Close()
+	assert.Nil(t, err)
+	inf, err = c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+}
+
+func TestHandoffLines(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offsets := platform.FloatingLines()
+
+	tx, rx := unixPair(t)
+	defer tx.Close()
+	defer rx.Close()
+
+	ll, err := c.RequestLines(offsets, gpiod.AsOutput(1, 0))
+	require.Nil(t, err)
+	require.NotNil(t, ll)
+
+	err = ll.Export(tx)
+	require.Nil(t, err)
+
+	// multiple lines cannot be imported as a Line
+	_, err = gpiod.ImportLine(rx)
+	assert.Equal(t, gpiod.ErrInvalidHandoff, err)
+	inf, err := c.LineInfo(offsets[0])
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+
+	ll, err = c.RequestLines(offsets, gpiod.AsOutput(1, 0))
+	require.Nil(t, err)
+	err = ll.Export(tx)
+	require.Nil(t, err)
+	il, err := gpiod.ImportLines(rx)
+	require.Nil(t, err)
+	require.NotNil(t, il)
+	defer il.Close()
+	assert.Equal(t, offsets, il.Offsets())
+	vv := make([]int, len(offsets))
+	err = il.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0}, vv[:2])
+}
+
+func TestHandoffEvents(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.IntrLine()
+
+	tx, rx := unixPair(t)
+	defer tx.Close()
+	defer rx.Close()
+
+	l, err := c.RequestLine(offset, gpiod.WithBothEdges(func(gpiod.LineEvent) {}))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	err = l.Export(tx)
+	require.Nil(t, err)
+
+	ich := make(chan gpiod.LineEvent, 3)
+	il, err := gpiod.ImportLine(rx, gpiod.WithBothEdges(func(evt gpiod.LineEvent) {
+		ich <- evt
+	}))
+	require.Nil(t, err)
+	require.NotNil(t, il)
+	defer il.Close()
+
+	platform.TriggerIntr(1)
+	waitEvent(t, ich, gpiod.LineEventRisingEdge)
+	platform.TriggerIntr(0)
+	waitEvent(t, ich, gpiod.LineEventFallingEdge)
+	select {
+	case evt := <-ich:
+		assert.Fail(t, "spurious event", evt)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -107,6 +107,13 @@ func (iw *infoWatcher) watch() {
 				Timestamp: time.Duration(lic.Timestamp),
 				Type:      LineInfoChangeType(lic.Type),
 			}
+			if lice.Type != LineReleased {
+				// the event only contains the v1 info, so the remaining
+				// attributes are read from the chip now, and reflect the
+				// state of the line when the event is read rather than
+				// when the change occurred.
+				addLineInfoV2(uintptr(fd), &lice.Info)
+			}
 			iw.ch(lice)
 		}
 	}
@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"sync"
+)
+
+// LineGroup represents a collection of requested lines which may be spread
+// across multiple chips.
+//
+// The lines are requested from each chip separately, so operations on the
+// group are not atomic across chips.
+type LineGroup struct {
+	names []string
+	cll   []ChipLine
+
+	// the requests from each chip, in order of first appearance in the group.
+	reqs []groupRequest
+
+	// mu covers closed.
+	mu     sync.Mutex
+	closed bool
+}
+
+type groupRequest struct {
+	ll *Lines
+
+	// the index of each line of the request within the group.
+	idx []int
+}
+
+// RequestLinesByName requests control of a collection of named lines.
+//
+// The lines are located using FindLines, and may be spread across multiple
+// chips.  The options are applied to all lines in the group.  Values in
+// options, such as AsOutput, are indexed by the position of the line in the
+// names.
+//
+// Event handlers may be called concurrently for lines on different chips.
+// Events are tagged with the chip and name of the line.
+func RequestLinesByName(names []string, options ...LineOption) (*LineGroup, error) {
+	cll, err := FindLines(names...)
+	if err != nil {
+		return nil, err
+	}
+	return requestLineGroup(names, cll, options...)
+}
+
+func requestLineGroup(names []string, cll []ChipLine, options ...LineOption) (*LineGroup, error) {
+	g := LineGroup{
+		names: append([]string(nil), names...),
+		cll:   cll,
+	}
+	chips := map[string]int{}
+	offsets := [][]int(nil)
+	for i, cl := range cll {
+		n, ok := chips[cl.Chip]
+		if !ok {
+			n = len(g.reqs)
+			chips[cl.Chip] = n
+			g.reqs = append(g.reqs, groupRequest{})
+			offsets = append(offsets, nil)
+		}
+		g.reqs[n].idx = append(g.reqs[n].idx, i)
+		offsets[n] = append(offsets[n], cl.Offset)
+	}
+	for n, r := range g.reqs {
+		onames := make(map[int]string, len(r.idx))
+		for _, i := range r.idx {
+			onames[cll[i].Offset] = names[i]
+		}
+		opts := namedLineOptions(groupLineOptions(options, r.idx), onames)
+		ll, err := requestGroupLines(cll[r.idx[0]].Chip, offsets[n], opts)
+		if err != nil {
+			for _, r := range g.reqs[:n] {
+				r.ll.Close()
+			}
+			return nil, err
+		}
+		g.reqs[n].ll = ll
+	}
+	return &g, nil
+}
+
+func requestGroupLines(name string, offsets []int, options []LineOption) (*Lines, error) {
+	c, err := NewChip(name)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.RequestLines(offsets, options...)
+}
+
+// groupedOption is implemented by options carrying values indexed by the
+// position of the line in a group, such as AsOutput, so the values can be
+// mapped to the subset of the lines requested from each chip.
+type groupedOption interface {
+	// subgroup returns the option with its values mapped to the subset of
+	// lines indicated by idx.
+	//
+	// The returned option has the same type as the receiver.
+	subgroup(idx []int) groupedOption
+}
+
+// groupLineOptions maps any group indexed values in the options to the values
+// for the subset of lines indicated by idx.
+func groupLineOptions(options []LineOption, idx []int) []LineOption {
+	opts := make([]LineOption, len(options))
+	for i, o := range options {
+		if g, ok := o.(groupedOption); ok {
+			o = g.subgroup(idx).(LineOption)
+		}
+		opts[i] = o
+	}
+	return opts
+}
+
+// groupLineConfigs maps any group indexed values in the configs to the values
+// for the subset of lines indicated by idx.
+func groupLineConfigs(configs []LineConfig, idx []int) []LineConfig {
+	cfgs := make([]LineConfig, len(configs))
+	for i, c := range configs {
+		if g, ok := c.(groupedOption); ok {
+			c = g.subgroup(idx).(LineConfig)
+		}
+		cfgs[i] = c
+	}
+	return cfgs
+}
+
+// groupValues returns the subset of the values indicated by idx.
+//
+// Values missing from the group are inactive.
+func groupValues(values []int, idx []int) []int {
+	vv := make([]int, len(idx))
+	for i, n := range idx {
+		if n < len(values) {
+			vv[i] = values[n]
+		}
+	}
+	return vv
+}
+
+// Names returns the names of the lines in the group.
+func (g *LineGroup) Names() []string {
+	return g.names
+}
+
+// Lines returns the chip and offset of the lines in the group.
+func (g *LineGroup) Lines() []ChipLine {
+	return g.cll
+}
+
+// Close releases all resources held by the group.
+func (g *LineGroup) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	g.closed = true
+	for _, r := range g.reqs {
+		r.ll.Close()
+	}
+	return nil
+}
+
+// Info returns the information about the lines in the group.
+func (g *LineGroup) Info() ([]*LineInfo, error) {
+	info := make([]*LineInfo, len(g.cll))
+	for _, r := range g.reqs {
+		li, err := r.ll.Info()
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range r.idx {
+			info[n] = li[i]
+		}
+	}
+	return info, nil
+}
+
+// Reconfigure updates the configuration of the lines in the group.
+//
+// Configuration for options other than those passed in remain unchanged.
+// Values in options, such as AsOutput, are indexed by the position of the line
+// in the group.
+//
+// Not valid for lines with edge detection enabled.
+//
+// Requires Linux v5.5 or later.
+func (g *LineGroup) Reconfigure(options ...LineConfig) error {
+	for _, r := range g.reqs {
+		err := r.ll.Reconfigure(groupLineConfigs(options, r.idx)...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Values returns the current values (active state) of the lines in the group.
+//
+// Gets as many values from the group, in order, as can be fit in values, up to
+// the full group.
+func (g *LineGroup) Values(values []int) error {
+	for _, r := range g.reqs {
+		vv := make([]int, len(r.idx))
+		err := r.ll.Values(vv)
+		if err != nil {
+			return err
+		}
+		for i, n := range r.idx {
+			if n < len(values) {
+				values[n] = vv[i]
+			}
+		}
+	}
+	return nil
+}
+
+// SetValues sets the current active state of the lines in the group.
+//
+// Only valid for output lines.
+//
+// The lines on each chip are set at once, but the chips are set in turn.  If
+// insufficient values are provided then the remaining lines are set to
+// inactive.
+func (g *LineGroup) SetValues(values []int) error {
+	if len(values) > len(g.cll) {
+		return ErrInvalidOffset
+	}
+	for _, r := range g.reqs {
+		err := r.ll.SetValues(groupValues(values, r.idx))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+)
+
+func TestRequestLinesByName(t *testing.T) {
+	names := floatingLineNames(t)
+
+	// nonexistent
+	g, err := gpiod.RequestLinesByName([]string{names[0], "nonexistent"})
+	assert.Equal(t, gpiod.ErrLineNotFound, err)
+	require.Nil(t, g)
+
+	// success
+	g, err = gpiod.RequestLinesByName(names, gpiod.AsOutput(1, 0))
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+	assert.Equal(t, names, g.Names())
+	xcll := []gpiod.ChipLine(nil)
+	for _, o := range platform.FloatingLines() {
+		xcll = append(xcll, gpiod.ChipLine{Chip: platform.Name(), Offset: o})
+	}
+	assert.Equal(t, xcll, g.Lines())
+
+	// already requested
+	g2, err := gpiod.RequestLinesByName(names)
+	assert.NotNil(t, err)
+	require.Nil(t, g2)
+
+	err = g.Close()
+	assert.Nil(t, err)
+	err = g.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestLineGroupInfo(t *testing.T) {
+	names := floatingLineNames(t)
+	c := getChip(t)
+	defer c.Close()
+
+	g, err := gpiod.RequestLinesByName(names)
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+
+	li, err := g.Info()
+	assert.Nil(t, err)
+	require.Equal(t, len(names), len(li))
+	for i, o := range platform.FloatingLines() {
+		cli, err := c.LineInfo(o)
+		assert.Nil(t, err)
+		require.NotNil(t, li[i])
+		assert.Equal(t, cli, *li[i])
+	}
+
+	g.Close()
+	li, err = g.Info()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, li)
+}
+
+func TestLineGroupValues(t *testing.T) {
+	if len(platform.IntrName()) == 0 {
+		t.Skip("platform lines are unnamed")
+	}
+	platform.TriggerIntr(0)
+	names := append([]string{platform.IntrName()}, floatingLineNames(t)...)
+	g, err := gpiod.RequestLinesByName(names)
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+	vv := make([]int, len(names))
+	err = g.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, vv[0])
+	platform.TriggerIntr(1)
+	err = g.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, vv[0])
+
+	// subset
+	vv = vv[:1]
+	err = g.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, vv[0])
+
+	g.Close()
+	err = g.Values(vv)
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestLineGroupSetValues(t *testing.T) {
+	names := floatingLineNames(t)
+	c := getChip(t)
+	defer c.Close()
+
+	// input
+	g, err := gpiod.RequestLinesByName(names)
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+	err = g.SetValues([]int{0, 1})
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+	g.Close()
+
+	// output
+	g, err = gpiod.RequestLinesByName(names, gpiod.AsOutput(0))
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+	err = g.SetValues([]int{1, 0})
+	assert.Nil(t, err)
+	vv := make([]int, len(names))
+	err = g.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0}, vv)
+
+	// too many values
+	err = g.SetValues([]int{1, 1, 1})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+
+	g.Close()
+	err = g.SetValues([]int{0, 1})
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestLineGroupReconfigure(t *testing.T) {
+	requireCapability(t, gpiod.CapSetConfig)
+
+	names := floatingLineNames(t)
+	c := getChip(t)
+	defer c.Close()
+
+	g, err := gpiod.RequestLinesByName(names)
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+
+	err = g.Reconfigure(gpiod.AsOutput(0, 1))
+	assert.Nil(t, err)
+	for _, o := range platform.FloatingLines() {
+		inf, err := c.LineInfo(o)
+		assert.Nil(t, err)
+		assert.True(t, inf.IsOut)
+	}
+	vv := make([]int, len(names))
+	err = g.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1}, vv)
+
+	g.Close()
+	err = g.Reconfigure(gpiod.AsInput)
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestLineGroupEvents(t *testing.T) {
+	if len(platform.IntrName()) == 0 {
+		t.Skip("platform lines are unnamed")
+	}
+	platform.TriggerIntr(0)
+	ich := make(chan gpiod.LineEvent, 3)
+	g, err := gpiod.RequestLinesByName([]string{platform.IntrName()},
+		gpiod.WithBothEdges(func(evt gpiod.LineEvent) {
+			ich <- evt
+		}))
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+	defer g.Close()
+	platform.TriggerIntr(1)
+	select {
+	case evt := <-ich:
+		assert.Equal(t, gpiod.LineEventRisingEdge, evt.Type)
+		assert.Equal(t, platform.Name(), evt.Chip)
+		assert.Equal(t, platform.IntrLine(), evt.Offset)
+		assert.Equal(t, platform.IntrName(), evt.Name)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+}
+
+func TestLineGroupOnClose(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+	requireCapability(t, gpiod.CapSetConfig)
+
+	names := floatingLineNames(t)
+	oname := otherChipLineName(t)
+	names = append(names[:1], oname)
+	cname, offset, err := gpiod.FindLine(oname)
+	require.Nil(t, err)
+	oc, err := gpiod.NewChip(cname)
+	require.Nil(t, err)
+	defer oc.Close()
+
+	wc := make(chan gpiod.LineInfoChangeEvent, 3)
+	_, err = oc.WatchLineInfo(offset, func(info gpiod.LineInfoChangeEvent) {
+		wc <- info
+	})
+	require.Nil(t, err)
+	defer oc.UnwatchLineInfo(offset)
+
+	// values are indexed by position in the group, not within each chip.
+	g, err := gpiod.RequestLinesByName(names, gpiod.AsOutput(1, 0),
+		gpiod.OnClose(gpiod.AsOutput(0, 1), gpiod.AsActiveLow))
+	assert.Nil(t, err)
+	require.NotNil(t, g)
+	waitInfoEvent(t, wc, gpiod.LineRequested)
+
+	err = g.Close()
+	assert.Nil(t, err)
+	select {
+	case evt := <-wc:
+		assert.Equal(t, gpiod.LineReconfigured, evt.Type)
+		assert.True(t, evt.Info.IsOut)
+		assert.True(t, evt.Info.ActiveLow)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+	waitInfoEvent(t, wc, gpiod.LineReleased)
+}
+
+// otherChipLineName returns the name of a line on a chip other than the
+// platform chip, or skips the test if there is none.
+func otherChipLineName(t *testing.T) string {
+	t.Helper()
+	for _, cname := range gpiod.Chips() {
+		if cname == platform.Name() {
+			continue
+		}
+		c, err := gpiod.NewChip(cname)
+		if err != nil {
+			continue
+		}
+		inf, err := c.LineInfo(0)
+		c.Close()
+		if err == nil && len(inf.Name) != 0 && !inf.Requested {
+			return inf.Name
+		}
+	}
+	t.Skip("no named line on another chip")
+	return ""
+}
+
+// floatingLineNames returns the names of the platform floating lines, or skips
+// the test if they are unnamed.
+func floatingLineNames(t *testing.T) []string {
+	t.Helper()
+	c := getChip(t)
+	defer c.Close()
+	names := []string(nil)
+	for _, o := range platform.FloatingLines() {
+		inf, err := c.LineInfo(o)
+		require.Nil(t, err)
+		if len(inf.Name) == 0 {
+			t.Skip("platform lines are unnamed")
+		}
+		names = append(names, inf.Name)
+	}
+	return names
+}
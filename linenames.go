@@ -0,0 +1,321 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// ChipLine identifies a line by the name of its chip and its offset within
+// the chip.
+type ChipLine struct {
+	// The name of the chip containing the line.
+	Chip string
+
+	// The offset of the line within the chip.
+	Offset int
+}
+
+// FindLines finds the chip and offset of each of the named lines.
+//
+// The lines may be spread across multiple chips.
+//
+// Returns an error unless all the lines are found.
+func FindLines(names ...string) ([]ChipLine, error) {
+	cll := make([]ChipLine, len(names))
+	found := make([]bool, len(names))
+	remaining := len(names)
+	for _, cn := range index.chips() {
+		for i, name := range names {
+			if found[i] {
+				continue
+			}
+			if o, ok := cn.ln.find(name); ok {
+				cll[i] = ChipLine{cn.chip, o}
+				found[i] = true
+				remaining--
+			}
+		}
+		if remaining == 0 {
+			return cll, nil
+		}
+	}
+	return nil, ErrLineNotFound
+}
+
+// MatchLines returns the chip and offset of all lines with names that match
+// the pattern.
+//
+// The pattern syntax is that of filepath.Match, e.g. "LED*" or "GPIO1?".
+//
+// Returns an error if the pattern is malformed.
+func MatchLines(pattern string) ([]ChipLine, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return matchLines(globMatcher(pattern)), nil
+}
+
+// MatchLinesRegexp returns the chip and offset of all lines with names that
+// match the regular expression.
+func MatchLinesRegexp(re *regexp.Regexp) []ChipLine {
+	return matchLines(re.MatchString)
+}
+
+// DuplicateLineNames returns the names that are used by more than one line,
+// and the chip and offset of each of those lines.
+//
+// Unnamed lines are not considered duplicates.
+func DuplicateLineNames() map[string][]ChipLine {
+	all := map[string][]ChipLine{}
+	for _, cn := range index.chips() {
+		for o, name := range cn.ln.names {
+			if len(name) != 0 {
+				all[name] = append(all[name], ChipLine{cn.chip, o})
+			}
+		}
+	}
+	dups := map[string][]ChipLine{}
+	for name, cll := range all {
+		if len(cll) > 1 {
+			dups[name] = cll
+		}
+	}
+	return dups
+}
+
+// InvalidateLineIndex discards the cached line names for all chips, forcing
+// them to be re-read on the next lookup.
+//
+// The index is revalidated against the chip devices on each lookup, so this
+// is only necessary if line names are changed on an existing chip.
+func InvalidateLineIndex() {
+	index.invalidate()
+}
+
+func matchLines(match func(string) bool) []ChipLine {
+	cll := []ChipLine(nil)
+	for _, cn := range index.chips() {
+		for _, o := range cn.ln.match(match) {
+			cll = append(cll, ChipLine{cn.chip, o})
+		}
+	}
+	return cll
+}
+
+// MatchLines returns the offsets of all lines with names that match the
+// pattern.
+//
+// The pattern syntax is that of filepath.Match, e.g. "LED*" or "GPIO1?".
+//
+// Returns an error if the pattern is malformed.
+func (c *Chip) MatchLines(pattern string) ([]int, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	ln, err := c.lineNames()
+	if err != nil {
+		return nil, err
+	}
+	return ln.match(globMatcher(pattern)), nil
+}
+
+// MatchLinesRegexp returns the offsets of all lines with names that match the
+// regular expression.
+func (c *Chip) MatchLinesRegexp(re *regexp.Regexp) ([]int, error) {
+	ln, err := c.lineNames()
+	if err != nil {
+		return nil, err
+	}
+	return ln.match(re.MatchString), nil
+}
+
+// DuplicateLineNames returns the names that are used by more than one line on
+// the chip, and the offsets of those lines.
+//
+// Unnamed lines are not considered duplicates.
+func (c *Chip) DuplicateLineNames() (map[string][]int, error) {
+	ln, err := c.lineNames()
+	if err != nil {
+		return nil, err
+	}
+	return ln.duplicates(), nil
+}
+
+// InvalidateLineNames discards the cached line names, forcing them to be
+// re-read from the chip on the next lookup.
+//
+// Names of lines being watched are updated automatically from the info
+// change events.
+func (c *Chip) InvalidateLineNames() {
+	c.mu.Lock()
+	c.ln = nil
+	c.mu.Unlock()
+}
+
+// lineNames returns the name index for the chip, building it if necessary.
+func (c *Chip) lineNames() (*lineNames, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	if c.sysfs != nil {
+		// sysfs does not provide line names.
+		return nil, ErrorSysfsUnsupported{"line names"}
+	}
+	if c.ln != nil {
+		return c.ln, nil
+	}
+	names := make([]string, c.lines)
+	for o := 0; o < c.lines; o++ {
+		li, err := uapi.GetLineInfo(c.f.Fd(), o)
+		if err != nil {
+			return nil, err
+		}
+		names[o] = uapi.BytesToString(li.Name[:])
+	}
+	c.ln = newLineNames(names)
+	return c.ln, nil
+}
+
+// lineNames is an index of the names of the lines on a chip.
+//
+// The index is immutable - changes are applied by replacing it.
+type lineNames struct {
+	// the name of each line, indexed by offset.
+	names []string
+
+	// the offsets of lines with each name, in ascending order.
+	offsets map[string][]int
+}
+
+func newLineNames(names []string) *lineNames {
+	ln := lineNames{
+		names:   names,
+		offsets: make(map[string][]int),
+	}
+	for o, name := range names {
+		ln.offsets[name] = append(ln.offsets[name], o)
+	}
+	return &ln
+}
+
+// find returns the lowest offset of a line with the name.
+func (ln *lineNames) find(name string) (int, bool) {
+	oo := ln.offsets[name]
+	if len(oo) == 0 {
+		return 0, false
+	}
+	return oo[0], true
+}
+
+func (ln *lineNames) match(match func(string) bool) []int {
+	oo := []int(nil)
+	for o, name := range ln.names {
+		if match(name) {
+			oo = append(oo, o)
+		}
+	}
+	return oo
+}
+
+func (ln *lineNames) duplicates() map[string][]int {
+	dups := map[string][]int{}
+	for name, oo := range ln.offsets {
+		if len(name) != 0 && len(oo) > 1 {
+			dups[name] = append([]int(nil), oo...)
+		}
+	}
+	return dups
+}
+
+// rename returns a copy of the index with the named line renamed.
+func (ln *lineNames) rename(offset int, name string) *lineNames {
+	if offset < 0 || offset >= len(ln.names) || ln.names[offset] == name {
+		return ln
+	}
+	names := append([]string(nil), ln.names...)
+	names[offset] = name
+	return newLineNames(names)
+}
+
+func globMatcher(pattern string) func(string) bool {
+	return func(name string) bool {
+		m, _ := filepath.Match(pattern, name)
+		return m
+	}
+}
+
+// the process wide index of line names.
+var index = lineIndex{entries: map[string]indexEntry{}}
+
+// lineIndex caches the line names of all chips in the system.
+type lineIndex struct {
+	mu      sync.Mutex
+	entries map[string]indexEntry
+}
+
+type indexEntry struct {
+	// identifies the instance of the chip device.
+	dev  uint64
+	ctim unix.Timespec
+
+	ln *lineNames
+}
+
+type chipNamesEntry struct {
+	chip string
+	ln   *lineNames
+}
+
+// chips returns the line names of all chips currently in the system.
+//
+// Entries are revalidated against the device node, which changes if the chip
+// is removed and re-added, so the cost of a lookup on an unchanged chip is a
+// stat rather than an ioctl per line.
+func (li *lineIndex) chips() []chipNamesEntry {
+	names := chipNames()
+	cc := []chipNamesEntry(nil)
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	entries := make(map[string]indexEntry, len(names))
+	for _, name := range names {
+		var stat unix.Stat_t
+		if err := unix.Stat(nameToPath(name), &stat); err != nil {
+			continue
+		}
+		e, ok := li.entries[name]
+		if !ok || e.dev != uint64(stat.Rdev) || e.ctim != stat.Ctim {
+			c, err := NewChip(name)
+			if err != nil {
+				continue
+			}
+			ln, err := c.lineNames()
+			c.Close()
+			if err != nil {
+				continue
+			}
+			e = indexEntry{dev: uint64(stat.Rdev), ctim: stat.Ctim, ln: ln}
+		}
+		entries[name] = e
+		cc = append(cc, chipNamesEntry{name, e.ln})
+	}
+	li.entries = entries
+	return cc
+}
+
+func (li *lineIndex) invalidate() {
+	li.mu.Lock()
+	li.entries = map[string]indexEntry{}
+	li.mu.Unlock()
+}
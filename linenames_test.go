@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+)
+
+func TestFindLines(t *testing.T) {
+	intr := platform.IntrLine()
+	// hacky workaround for unnamed lines on RPi
+	if len(platform.IntrName()) == 0 {
+		intr = 0
+	}
+	xcl := gpiod.ChipLine{Chip: platform.Name(), Offset: intr}
+
+	cll, err := gpiod.FindLines(platform.IntrName(), platform.IntrName())
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiod.ChipLine{xcl, xcl}, cll)
+
+	// cached
+	cll, err = gpiod.FindLines(platform.IntrName())
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiod.ChipLine{xcl}, cll)
+
+	// invalidated
+	gpiod.InvalidateLineIndex()
+	cll, err = gpiod.FindLines(platform.IntrName())
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiod.ChipLine{xcl}, cll)
+
+	cll, err = gpiod.FindLines(platform.IntrName(), "nonexistent")
+	assert.Equal(t, gpiod.ErrLineNotFound, err)
+	assert.Nil(t, cll)
+}
+
+func TestMatchLines(t *testing.T) {
+	if len(platform.IntrName()) == 0 {
+		t.Skip("platform lines are unnamed")
+	}
+	xcl := gpiod.ChipLine{Chip: platform.Name(), Offset: platform.IntrLine()}
+
+	// exact
+	cll, err := gpiod.MatchLines(platform.IntrName())
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiod.ChipLine{xcl}, cll)
+
+	// wildcard
+	cll, err = gpiod.MatchLines(platform.IntrName()[:len(platform.IntrName())-1] + "*")
+	assert.Nil(t, err)
+	assert.Contains(t, cll, xcl)
+
+	// no match
+	cll, err = gpiod.MatchLines("nonexistent*")
+	assert.Nil(t, err)
+	assert.Empty(t, cll)
+
+	// malformed
+	cll, err = gpiod.MatchLines("[")
+	assert.Equal(t, filepath.ErrBadPattern, err)
+	assert.Nil(t, cll)
+}
+
+func TestMatchLinesRegexp(t *testing.T) {
+	if len(platform.IntrName()) == 0 {
+		t.Skip("platform lines are unnamed")
+	}
+	xcl := gpiod.ChipLine{Chip: platform.Name(), Offset: platform.IntrLine()}
+
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(platform.IntrName()) + "$")
+	cll := gpiod.MatchLinesRegexp(re)
+	assert.Equal(t, []gpiod.ChipLine{xcl}, cll)
+
+	cll = gpiod.MatchLinesRegexp(regexp.MustCompile("^nonexistent$"))
+	assert.Empty(t, cll)
+}
+
+func TestDuplicateLineNames(t *testing.T) {
+	dups := gpiod.DuplicateLineNames()
+	require.NotNil(t, dups)
+	for name, cll := range dups {
+		assert.NotEmpty(t, name)
+		assert.Greater(t, len(cll), 1)
+	}
+}
+
+func TestChipMatchLines(t *testing.T) {
+	if len(platform.IntrName()) == 0 {
+		t.Skip("platform lines are unnamed")
+	}
+	c := getChip(t)
+
+	oo, err := c.MatchLines(platform.IntrName())
+	assert.Nil(t, err)
+	assert.Equal(t, []int{platform.IntrLine()}, oo)
+
+	oo, err = c.MatchLines("*")
+	assert.Nil(t, err)
+	assert.Equal(t, platform.Lines(), len(oo))
+
+	oo, err = c.MatchLines("[")
+	assert.Equal(t, filepath.ErrBadPattern, err)
+	assert.Nil(t, oo)
+
+	c.Close()
+	oo, err = c.MatchLines("*")
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, oo)
+}
+
+func TestChipMatchLinesRegexp(t *testing.T) {
+	if len(platform.IntrName()) == 0 {
+		t.Skip("platform lines are unnamed")
+	}
+	c := getChip(t)
+
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(platform.IntrName()) + "$")
+	oo, err := c.MatchLinesRegexp(re)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{platform.IntrLine()}, oo)
+
+	c.Close()
+	oo, err = c.MatchLinesRegexp(re)
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, oo)
+}
+
+func TestChipDuplicateLineNames(t *testing.T) {
+	c := getChip(t)
+
+	dups, err := c.DuplicateLineNames()
+	assert.Nil(t, err)
+	require.NotNil(t, dups)
+	for name, oo := range dups {
+		assert.NotEmpty(t, name)
+		assert.Greater(t, len(oo), 1)
+	}
+
+	c.Close()
+	dups, err = c.DuplicateLineNames()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, dups)
+}
+
+func TestChipInvalidateLineNames(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	o, err := c.FindLine(platform.IntrName())
+	assert.Nil(t, err)
+	c.InvalidateLineNames()
+	o2, err := c.FindLine(platform.IntrName())
+	assert.Nil(t, err)
+	assert.Equal(t, o, o2)
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"sync"
+)
+
+// LineManager shares requested lines between the components of a program.
+//
+// Input lines may be shared by any number of components, each holding its own
+// SharedLine handle to a single kernel request, and each receiving the edge
+// events from the line.  The kernel request is released when the last handle
+// is closed.
+//
+// Output lines are not shared, and are requested directly from the chip, so a
+// line held as an output cannot also be held as an input, or vice versa.
+type LineManager struct {
+	c *Chip
+
+	// the options applied to shared inputs.
+	options []LineOption
+
+	// mu covers inputs.
+	mu sync.Mutex
+
+	// the shared inputs, keyed by offset.
+	inputs map[int]*sharedInput
+}
+
+// NewLineManager creates a LineManager for lines on the chip.
+//
+// The options are applied to all the input lines requested from the manager,
+// as the configuration of a shared line is common to all its handles.
+//
+// Input lines are requested with edge detection, so the events can be passed
+// to any handle that requires them.  For chips that cannot provide edge
+// interrupts the WithPolledEdges option should be provided.
+func NewLineManager(c *Chip, options ...LineOption) *LineManager {
+	return &LineManager{
+		c:       c,
+		options: append([]LineOption(nil), options...),
+		inputs:  map[int]*sharedInput{},
+	}
+}
+
+// RequestInput returns a handle to the line as a shared input.
+//
+// The line is requested from the chip if it is not already held by another
+// handle.
+//
+// Edge events detected on the line are passed to the event handler, if not
+// nil.  The handler is called from a goroutine shared by all handles to the
+// line, so it should not block, nor request or close handles.
+func (m *LineManager) RequestInput(offset int, eh EventHandler) (*SharedLine, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.inputs[offset]
+	if s == nil {
+		s = &sharedInput{}
+		options := append(append([]LineOption(nil), m.options...),
+			WithBothEdges(s.dispatch))
+		l, err := m.c.RequestLine(offset, options...)
+		if err != nil {
+			return nil, err
+		}
+		s.l = l
+		m.inputs[offset] = s
+	}
+	h := &SharedLine{m: m, s: s, eh: eh}
+	s.mu.Lock()
+	s.handles = append(s.handles, h)
+	s.mu.Unlock()
+	return h, nil
+}
+
+// RequestOutput requests the line as an output.
+//
+// Outputs are not shared, so this fails with unix.EBUSY if the line is
+// already held, either as an output or as a shared input.
+//
+// The line is requested as an output with an initial value of 0, unless
+// overridden by the options.
+func (m *LineManager) RequestOutput(offset int, options ...LineOption) (*Line, error) {
+	options = append([]LineOption{AsOutput()}, options...)
+	return m.c.RequestLine(offset, options...)
+}
+
+// sharedInput is an input line shared by a set of handles.
+type sharedInput struct {
+	// the requested line.
+	//
+	// Immutable once the sharedInput is added to the LineManager.
+	l *Line
+
+	// mu covers handles.
+	mu sync.Mutex
+
+	// the handles to the line, in the order they were created.
+	handles []*SharedLine
+}
+
+// dispatch passes an event to the handles to the line.
+func (s *sharedInput) dispatch(evt LineEvent) {
+	s.mu.Lock()
+	ehs := []EventHandler(nil)
+	for _, h := range s.handles {
+		if h.eh != nil {
+			ehs = append(ehs, h.eh)
+		}
+	}
+	s.mu.Unlock() // handlers called outside lock
+	for _, eh := range ehs {
+		eh(evt)
+	}
+}
+
+// remove closes and removes the handle, and returns the number of remaining
+// handles.
+func (s *sharedInput) remove(h *SharedLine) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h.closed {
+		return len(s.handles), ErrClosed
+	}
+	h.closed = true
+	for i, sh := range s.handles {
+		if sh == h {
+			s.handles = append(s.handles[:i], s.handles[i+1:]...)
+			break
+		}
+	}
+	return len(s.handles), nil
+}
+
+// SharedLine is a handle to an input line shared via a LineManager.
+type SharedLine struct {
+	m  *LineManager
+	s  *sharedInput
+	eh EventHandler
+
+	// set once the handle is closed.
+	//
+	// Covered by the sharedInput mutex.
+	closed bool
+}
+
+// Offset returns the offset of the line within the chip.
+func (h *SharedLine) Offset() int {
+	return h.s.l.Offset()
+}
+
+// Value returns the current value (active state) of the line.
+func (h *SharedLine) Value() (int, error) {
+	if h.isClosed() {
+		return 0, ErrClosed
+	}
+	return h.s.l.Value()
+}
+
+// Info returns the information about the line.
+func (h *SharedLine) Info() (LineInfo, error) {
+	if h.isClosed() {
+		return LineInfo{}, ErrClosed
+	}
+	return h.s.l.Info()
+}
+
+// Close releases the handle.
+//
+// The line is released once all handles to it have been closed.
+func (h *SharedLine) Close() error {
+	m := h.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := h.s.remove(h)
+	if n > 0 || err != nil {
+		return err
+	}
+	delete(m.inputs, h.s.l.Offset())
+	// the manager remains locked so the line cannot be requested again until
+	// it is released.
+	return h.s.l.Close()
+}
+
+func (h *SharedLine) isClosed() bool {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	return h.closed
+}
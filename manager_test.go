@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"golang.org/x/sys/unix"
+)
+
+func TestLineManagerInput(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	m := gpiod.NewLineManager(c, gpiod.WithConsumer("shared"))
+	offset := platform.IntrLine()
+	platform.TriggerIntr(0)
+
+	ech1 := make(chan gpiod.LineEvent, 3)
+	h1, err := m.RequestInput(offset, func(evt gpiod.LineEvent) {
+		ech1 <- evt
+	})
+	require.Nil(t, err)
+	require.NotNil(t, h1)
+	assert.Equal(t, offset, h1.Offset())
+	ech2 := make(chan gpiod.LineEvent, 3)
+	h2, err := m.RequestInput(offset, func(evt gpiod.LineEvent) {
+		ech2 <- evt
+	})
+	require.Nil(t, err)
+	require.NotNil(t, h2)
+
+	// no handler
+	h3, err := m.RequestInput(offset, nil)
+	require.Nil(t, err)
+	require.NotNil(t, h3)
+
+	// shared request
+	li, err := h1.Info()
+	assert.Nil(t, err)
+	assert.True(t, li.Requested)
+	assert.Equal(t, "shared", li.Consumer)
+	assert.False(t, li.IsOut)
+
+	// events fan out
+	platform.TriggerIntr(1)
+	waitEvent(t, ech1, gpiod.LineEventRisingEdge)
+	waitEvent(t, ech2, gpiod.LineEventRisingEdge)
+	v, err := h3.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// closed handles don't receive events
+	err = h1.Close()
+	assert.Nil(t, err)
+	err = h1.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	_, err = h1.Value()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	_, err = h1.Info()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	platform.TriggerIntr(0)
+	waitEvent(t, ech2, gpiod.LineEventFallingEdge)
+	waitNoEvent(t, ech1)
+
+	// outputs are exclusive
+	l, err := m.RequestOutput(offset)
+	assert.Equal(t, unix.EBUSY, err)
+	assert.Nil(t, l)
+
+	// released with the last handle
+	err = h2.Close()
+	assert.Nil(t, err)
+	li, err = c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.True(t, li.Requested)
+	err = h3.Close()
+	assert.Nil(t, err)
+	li, err = c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.False(t, li.Requested)
+
+	// requested again
+	h1, err = m.RequestInput(offset, nil)
+	require.Nil(t, err)
+	require.NotNil(t, h1)
+	err = h1.Close()
+	assert.Nil(t, err)
+}
+
+func TestLineManagerOutput(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	m := gpiod.NewLineManager(c)
+	offset := platform.OutLine()
+
+	l, err := m.RequestOutput(offset, gpiod.AsOutput(1))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	li, err := l.Info()
+	assert.Nil(t, err)
+	assert.True(t, li.IsOut)
+	assert.Equal(t, 1, platform.ReadOut())
+
+	// exclusive
+	l2, err := m.RequestOutput(offset)
+	assert.Equal(t, unix.EBUSY, err)
+	assert.Nil(t, l2)
+	h, err := m.RequestInput(offset, nil)
+	assert.Equal(t, unix.EBUSY, err)
+	assert.Nil(t, h)
+
+	err = l.Close()
+	assert.Nil(t, err)
+}
@@ -42,9 +42,10 @@ type Chip struct {
 // A number of GPIO chips can be mocked, with the number of lines on each
 // specified in lines. e.g. []int{4,6} would create two chips, the first with 4
 // lines and the second with 6.
-// Requires the gpio-mockup kernel module and Linux 5.1.0 or later. Note
-// that only one Mockup can be present on a system at any time and that this
-// function unloads the gpio-mockup module if it is already loaded.
+// Requires the gpio-mockup kernel module, with support for reading and
+// pulling the line values via debugfs. Note that only one Mockup can be
+// present on a system at any time and that this function unloads the
+// gpio-mockup module if it is already loaded.
 func New(lines []int, namedLines bool) (*Mockup, error) {
 	if len(lines) == 0 {
 		return nil, unix.EINVAL
@@ -88,6 +89,12 @@ func New(lines []int, namedLines bool) (*Mockup, error) {
 	if err != nil {
 		return nil, err
 	}
+	// older modules only allow events to be injected via debugfs.
+	if cc[0].Lines > 0 {
+		if _, err = cc[0].Value(0); err != nil {
+			return nil, ErrorUnsupported{"reading line values"}
+		}
+	}
 	m := Mockup{cc: cc}
 	return &m, nil
 }
@@ -203,8 +210,20 @@ func (c *Chip) SetValue(line int, value int) error {
 }
 
 // IsSupported returns an error if this package cannot run on this platform.
+//
+// The gpio-mockup module must be available and support the parameters used
+// by New.
 func IsSupported() error {
-	return CheckKernelVersion(Semver{5, 1})
+	out, err := exec.Command("modinfo", "-F", "parm", "gpio-mockup").Output()
+	if err != nil {
+		return fmt.Errorf("can't find gpio-mockup module: %s", err)
+	}
+	for _, parm := range []string{"gpio_mockup_ranges", "gpio_mockup_named_lines"} {
+		if !bytes.Contains(out, []byte(parm)) {
+			return ErrorUnsupported{parm}
+		}
+	}
+	return nil
 }
 
 // KernelVersion returns the running kernel version.
@@ -272,6 +291,16 @@ func (e ErrorIndexRange) Error() string {
 	return fmt.Sprintf("index out of range - got %d, limit is %d.", e.Req, e.Limit)
 }
 
+// ErrorUnsupported indicates the gpio-mockup module does not support a
+// feature required by this package.
+type ErrorUnsupported struct {
+	Feature string
+}
+
+func (e ErrorUnsupported) Error() string {
+	return fmt.Sprintf("gpio-mockup does not support %s", e.Feature)
+}
+
 // ErrorBadVersion indicates the kernel version is insufficient.
 type ErrorBadVersion struct {
 	Need Semver
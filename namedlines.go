@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"sort"
+	"sync"
+)
+
+// NamedLines represents a collection of requested lines, each identified by a
+// logical name.
+type NamedLines struct {
+	ll *Lines
+
+	// the logical names of the lines, in the same order as ll.offsets.
+	names []string
+
+	// the index of each line in ll.offsets, keyed by name.
+	idx map[string]int
+
+	// mu serialises updates to the output values.
+	mu sync.Mutex
+}
+
+// RequestNamedLines requests control of a collection of lines on the chip,
+// each identified by a logical name.
+//
+// The lines are provided as a map from logical name to offset.  The logical
+// names may be the line names on the chip, as returned by FindLineMap, or
+// names specific to the application.
+//
+// The lines are requested in order of ascending offset, so values in options,
+// such as AsOutput, are indexed in that order, which is the order returned by
+// Names.  Events are tagged with the logical name of the line.
+//
+// Returns an ErrorDuplicateLine if two names map to the same offset.
+func (c *Chip) RequestNamedLines(lines map[string]int, options ...LineOption) (*NamedLines, error) {
+	names := make([]string, 0, len(lines))
+	for name := range lines {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if lines[names[i]] == lines[names[j]] {
+			return names[i] < names[j]
+		}
+		return lines[names[i]] < lines[names[j]]
+	})
+	for i := 1; i < len(names); i++ {
+		if o := lines[names[i]]; o == lines[names[i-1]] {
+			return nil, ErrorDuplicateLine{[2]string{names[i-1], names[i]}, o}
+		}
+	}
+	offsets := make([]int, len(names))
+	idx := make(map[string]int, len(names))
+	onames := make(map[int]string, len(names))
+	for i, name := range names {
+		offsets[i] = lines[name]
+		idx[name] = i
+		onames[lines[name]] = name
+	}
+	ll, err := c.RequestLines(offsets, namedLineOptions(options, onames)...)
+	if err != nil {
+		return nil, err
+	}
+	nl := NamedLines{
+		ll:    ll,
+		names: names,
+		idx:   idx,
+	}
+	return &nl, nil
+}
+
+// FindLineMap returns a map from line name to offset for the named lines, or
+// an error unless all are found.
+//
+// The map can be used to request the lines using RequestNamedLines.
+func (c *Chip) FindLineMap(names ...string) (map[string]int, error) {
+	oo, err := c.FindLines(names...)
+	if err != nil {
+		return nil, err
+	}
+	lines := make(map[string]int, len(names))
+	for i, name := range names {
+		lines[name] = oo[i]
+	}
+	return lines, nil
+}
+
+// namedLineOptions wraps any event handlers in the options so that events are
+// tagged with the name of the line, as provided by the names, keyed by
+// offset.
+func namedLineOptions(options []LineOption, names map[int]string) []LineOption {
+	opts := make([]LineOption, len(options))
+	for i, o := range options {
+		if eo, ok := o.(EdgeOption); ok && eo.e != nil {
+			eh := eo.e
+			eo.e = func(evt LineEvent) {
+				evt.Name = names[evt.Offset]
+				eh(evt)
+			}
+			o = eo
+		}
+		opts[i] = o
+	}
+	return opts
+}
+
+// Chip returns the name of the chip from which the lines were requested.
+func (nl *NamedLines) Chip() string {
+	return nl.ll.Chip()
+}
+
+// Close releases all resources held by the requested lines.
+func (nl *NamedLines) Close() error {
+	return nl.ll.Close()
+}
+
+// Names returns the logical names of the lines, in order of ascending offset.
+func (nl *NamedLines) Names() []string {
+	return nl.names
+}
+
+// Offsets returns the offsets of the lines, keyed by logical name.
+func (nl *NamedLines) Offsets() map[string]int {
+	offsets := make(map[string]int, len(nl.names))
+	for i, name := range nl.names {
+		offsets[name] = nl.ll.offsets[i]
+	}
+	return offsets
+}
+
+// Info returns the information about the lines, keyed by logical name.
+func (nl *NamedLines) Info() (map[string]*LineInfo, error) {
+	li, err := nl.ll.Info()
+	if err != nil {
+		return nil, err
+	}
+	info := make(map[string]*LineInfo, len(nl.names))
+	for i, name := range nl.names {
+		info[name] = li[i]
+	}
+	return info, nil
+}
+
+// Reconfigure updates the configuration of the requested lines.
+//
+// Configuration for options other than those passed in remain unchanged.
+// Values in options, such as AsOutput, are indexed in the order returned by
+// Names.
+//
+// Not valid for lines with edge detection enabled.
+//
+// Requires Linux v5.5 or later.
+func (nl *NamedLines) Reconfigure(options ...LineConfig) error {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	return nl.ll.Reconfigure(options...)
+}
+
+// Value returns the current value (active state) of the named line.
+func (nl *NamedLines) Value(name string) (int, error) {
+	i, ok := nl.idx[name]
+	if !ok {
+		return 0, ErrLineNotFound
+	}
+	vv := make([]int, len(nl.names))
+	err := nl.ll.Values(vv)
+	if err != nil {
+		return 0, err
+	}
+	return vv[i], nil
+}
+
+// Values returns the current values (active state) of the lines, keyed by
+// logical name.
+func (nl *NamedLines) Values() (map[string]int, error) {
+	vv := make([]int, len(nl.names))
+	err := nl.ll.Values(vv)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]int, len(nl.names))
+	for i, name := range nl.names {
+		values[name] = vv[i]
+	}
+	return values, nil
+}
+
+// SetValue sets the current active state of the named line.
+//
+// The state of the other lines is unchanged.
+//
+// Only valid for output lines.
+func (nl *NamedLines) SetValue(name string, value int) error {
+	return nl.SetValues(map[string]int{name: value})
+}
+
+// SetValues sets the current active state of the lines, keyed by logical name.
+//
+// All lines are set at once.  Lines not contained in values retain their
+// current state.
+//
+// Only valid for output lines.
+func (nl *NamedLines) SetValues(values map[string]int) error {
+	for name := range values {
+		if _, ok := nl.idx[name]; !ok {
+			return ErrLineNotFound
+		}
+	}
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	nl.ll.mu.Lock()
+	vv := make([]int, len(nl.names))
+	copy(vv, nl.ll.outputValues)
+	nl.ll.mu.Unlock()
+	for name, v := range values {
+		vv[nl.idx[name]] = v
+	}
+	return nl.ll.SetValues(vv)
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+)
+
+func TestChipRequestNamedLines(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	ff := platform.FloatingLines()
+	lines := map[string]int{"b": ff[0], "a": ff[1]}
+
+	// out of range
+	nl, err := c.RequestNamedLines(map[string]int{"a": ff[0], "b": c.Lines()})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+	require.Nil(t, nl)
+
+	// duplicate
+	nl, err = c.RequestNamedLines(map[string]int{"b": ff[0], "a": ff[0], "c": ff[1]})
+	assert.Equal(t, gpiod.ErrorDuplicateLine{[2]string{"a", "b"}, ff[0]}, err)
+	assert.Equal(t, fmt.Sprintf("lines 'a' and 'b' are both offset %d", ff[0]), err.Error())
+	require.Nil(t, nl)
+
+	// success
+	nl, err = c.RequestNamedLines(lines, gpiod.AsOutput(1, 0))
+	assert.Nil(t, err)
+	require.NotNil(t, nl)
+	assert.Equal(t, []string{"b", "a"}, nl.Names())
+	assert.Equal(t, lines, nl.Offsets())
+	assert.Equal(t, c.Name, nl.Chip())
+	vv, err := nl.Values()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"b": 1, "a": 0}, vv)
+
+	// already requested
+	nl2, err := c.RequestNamedLines(lines)
+	assert.NotNil(t, err)
+	require.Nil(t, nl2)
+
+	err = nl.Close()
+	assert.Nil(t, err)
+	err = nl.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestChipFindLineMap(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	names := floatingLineNames(t)
+
+	lines, err := c.FindLineMap(names...)
+	assert.Nil(t, err)
+	ff := platform.FloatingLines()
+	assert.Equal(t, map[string]int{names[0]: ff[0], names[1]: ff[1]}, lines)
+
+	lines, err = c.FindLineMap(names[0], "nonexistent")
+	assert.Equal(t, gpiod.ErrLineNotFound, err)
+	assert.Nil(t, lines)
+}
+
+func TestNamedLinesInfo(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	ff := platform.FloatingLines()
+	nl, err := c.RequestNamedLines(map[string]int{"a": ff[0], "b": ff[1]})
+	assert.Nil(t, err)
+	require.NotNil(t, nl)
+
+	info, err := nl.Info()
+	assert.Nil(t, err)
+	require.Equal(t, 2, len(info))
+	for name, o := range map[string]int{"a": ff[0], "b": ff[1]} {
+		cli, err := c.LineInfo(o)
+		assert.Nil(t, err)
+		require.NotNil(t, info[name])
+		assert.Equal(t, cli, *info[name])
+	}
+
+	nl.Close()
+	info, err = nl.Info()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, info)
+}
+
+func TestNamedLinesValue(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	platform.TriggerIntr(0)
+	nl, err := c.RequestNamedLines(map[string]int{
+		"intr": platform.IntrLine(),
+		"f":    platform.FloatingLines()[0],
+	})
+	assert.Nil(t, err)
+	require.NotNil(t, nl)
+	v, err := nl.Value("intr")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+	platform.TriggerIntr(1)
+	v, err = nl.Value("intr")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	vv, err := nl.Values()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, vv["intr"])
+
+	// unknown
+	_, err = nl.Value("nonexistent")
+	assert.Equal(t, gpiod.ErrLineNotFound, err)
+
+	nl.Close()
+	_, err = nl.Value("intr")
+	assert.Equal(t, gpiod.ErrClosed, err)
+	vv, err = nl.Values()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, vv)
+}
+
+func TestNamedLinesSetValues(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	ff := platform.FloatingLines()
+	lines := map[string]int{"a": ff[0], "b": ff[1]}
+
+	// input
+	nl, err := c.RequestNamedLines(lines)
+	assert.Nil(t, err)
+	require.NotNil(t, nl)
+	err = nl.SetValue("a", 1)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+	nl.Close()
+
+	// output
+	nl, err = c.RequestNamedLines(lines, gpiod.AsOutput(0, 1))
+	assert.Nil(t, err)
+	require.NotNil(t, nl)
+	err = nl.SetValue("a", 1)
+	assert.Nil(t, err)
+	vv, err := nl.Values()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "b": 1}, vv)
+	err = nl.SetValues(map[string]int{"b": 0})
+	assert.Nil(t, err)
+	vv, err = nl.Values()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "b": 0}, vv)
+
+	// unknown
+	err = nl.SetValues(map[string]int{"a": 0, "nonexistent": 1})
+	assert.Equal(t, gpiod.ErrLineNotFound, err)
+
+	nl.Close()
+	err = nl.SetValue("a", 0)
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestNamedLinesEvents(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	platform.TriggerIntr(0)
+	ich := make(chan gpiod.LineEvent, 3)
+	nl, err := c.RequestNamedLines(map[string]int{"intr": platform.IntrLine()},
+		gpiod.WithBothEdges(func(evt gpiod.LineEvent) {
+			ich <- evt
+		}))
+	assert.Nil(t, err)
+	require.NotNil(t, nl)
+	defer nl.Close()
+	platform.TriggerIntr(1)
+	select {
+	case evt := <-ich:
+		assert.Equal(t, gpiod.LineEventRisingEdge, evt.Type)
+		assert.Equal(t, "intr", evt.Name)
+		assert.Equal(t, platform.IntrLine(), evt.Offset)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+}
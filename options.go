@@ -4,7 +4,13 @@
 
 package gpiod
 
-import "github.com/warthog618/gpiod/uapi"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/warthog618/gpiod/uapi"
+)
 
 // ChipOption defines the interface required to provide a Chip option.
 type ChipOption interface {
@@ -55,6 +61,12 @@ type LineOptions struct {
 	EventFlags    uapi.EventFlag
 	HandleFlags   uapi.HandleFlag
 	eh            EventHandler
+	wait          *WaitOption
+	closePolicy   []LineConfig
+	verify        *VerifyOption
+	emulate       *EmulationOption
+	poll          *PollOption
+	liveInfo      bool
 }
 
 // EventHandler is a receiver for line events.
@@ -113,8 +125,11 @@ type OutputOption struct {
 // If fewer values are provided than lines then the remaining lines default to
 // inactive.
 //
-// This option overrides and clears any previous Input, RisingEdge, FallingEdge,
-// or BothEdges options.
+// This option overrides and clears any previous Input option.  It conflicts
+// with any previous RisingEdge, FallingEdge, or BothEdges options, so the
+// request is rejected with ErrEdgeConflict.
+//
+// Providing more values than lines is rejected with ErrTooManyValues.
 func AsOutput(values ...int) OutputOption {
 	vv := append([]int(nil), values...)
 	return OutputOption{vv}
@@ -131,6 +146,10 @@ func (o OutputOption) applyLineConfig(l *LineOptions) {
 	o.applyLineOption(l)
 }
 
+func (o OutputOption) subgroup(idx []int) groupedOption {
+	return OutputOption{groupValues(o.initialValues, idx)}
+}
+
 // LevelOption determines the line level that is considered active.
 type LevelOption struct {
 	flag uapi.HandleFlag
@@ -184,19 +203,22 @@ func (o DriveOption) applyLineConfig(l *LineOptions) {
 // AsOpenDrain indicates that a line be driven low but left floating for high.
 //
 // This option sets the Output option and overrides and clears any previous
-// Input, RisingEdge, FallingEdge, BothEdges, or OpenSource options.
+// Input or OpenSource options.  It conflicts with any previous RisingEdge,
+// FallingEdge, or BothEdges options.
 var AsOpenDrain = DriveOption{uapi.HandleRequestOpenDrain}
 
 // AsOpenSource indicates that a line be driven low but left floating for high.
 //
 // This option sets the Output option and overrides and clears any previous
-// Input, RisingEdge, FallingEdge, BothEdges, or OpenDrain options.
+// Input or OpenDrain options.  It conflicts with any previous RisingEdge,
+// FallingEdge, or BothEdges options.
 var AsOpenSource = DriveOption{uapi.HandleRequestOpenSource}
 
 // AsPushPull indicates that a line be driven both low and high.
 //
 // This option sets the Output option and overrides and clears any previous
-// Input, RisingEdge, FallingEdge, BothEdges, OpenDrain, or OpenSource options.
+// Input, OpenDrain, or OpenSource options.  It conflicts with any previous
+// RisingEdge, FallingEdge, or BothEdges options.
 var AsPushPull = DriveOption{}
 
 // BiasOption indicates how a line is to be biased.
@@ -291,3 +313,274 @@ func WithRisingEdge(e func(LineEvent)) EdgeOption {
 func WithBothEdges(e func(LineEvent)) EdgeOption {
 	return EdgeOption{EventHandler(e), uapi.EventRequestBothEdges}
 }
+
+// WaitOption indicates that a request for lines which are already in use
+// should wait until the lines are released.
+type WaitOption struct {
+	ctx     context.Context
+	timeout time.Duration
+}
+
+func (o WaitOption) applyLineOption(l *LineOptions) {
+	l.wait = &o
+}
+
+// WithWait indicates that a request for lines which are already in use should
+// wait until the lines are released, or until the context is done.
+//
+// The context must not be nil.
+//
+// If the context is done before the lines can be requested then the request
+// fails with the context error.
+//
+// Requires Linux v5.7 or later.
+func WithWait(ctx context.Context) WaitOption {
+	return WaitOption{ctx: ctx}
+}
+
+// WithWaitTimeout indicates that a request for lines which are already in use
+// should wait until the lines are released, or until the timeout expires.
+//
+// If the timeout expires before the lines can be requested then the request
+// fails with context.DeadlineExceeded.
+//
+// Requires Linux v5.7 or later.
+func WithWaitTimeout(timeout time.Duration) WaitOption {
+	return WaitOption{timeout: timeout}
+}
+
+// ClosePolicyOption defines the configuration applied to lines when they are
+// closed.
+type ClosePolicyOption struct {
+	configs []LineConfig
+}
+
+func (o ClosePolicyOption) applyLineOption(l *LineOptions) {
+	l.closePolicy = o.configs
+}
+
+func (o ClosePolicyOption) subgroup(idx []int) groupedOption {
+	return ClosePolicyOption{groupLineConfigs(o.configs, idx)}
+}
+
+// OnClose indicates the configuration to be applied to lines by Close, before
+// the lines are released.
+//
+// This allows lines to be left in a known safe state, e.g.
+//
+//  gpiod.OnClose(gpiod.AsOutput(0))                // drive inactive
+//  gpiod.OnClose(gpiod.AsInput, gpiod.WithPullDown) // float with pull-down
+//
+// Note that the driver may still alter the state of the line when it is
+// released.
+//
+// If the configuration only changes output values then it is applied by
+// setting the values, otherwise it is applied as per Reconfigure, and so
+// requires Linux v5.5 or later.
+//
+// The close policy cannot be used with edge detection.
+func OnClose(configs ...LineConfig) ClosePolicyOption {
+	return ClosePolicyOption{append([]LineConfig(nil), configs...)}
+}
+
+// VerifyOption indicates that writes to output lines be verified by reading
+// back the line values.
+type VerifyOption struct {
+	settle time.Duration
+}
+
+func (o VerifyOption) applyLineOption(l *LineOptions) {
+	l.verify = &o
+}
+
+// WithVerifiedWrites indicates that each write to output lines, via SetValue
+// or SetValues, be verified by reading back the values of the lines after the
+// settle period.
+//
+// If the values read back do not match the values written then the write
+// returns an ErrorMismatch listing the offsets of the mismatched lines.
+//
+// This relies on the chip reading the physical level of output lines.  Some
+// chips return the value being driven instead, in which case mismatches are
+// not detected.
+func WithVerifiedWrites(settle time.Duration) VerifyOption {
+	return VerifyOption{settle}
+}
+
+// EmulationOption controls the emulation of the open drain and open source
+// drive options.
+//
+// By default the OpenDrain and OpenSource options are emulated if they are
+// rejected by the kernel, as many chips do not support them.
+//
+// The drive is emulated by switching each line to an input when it should
+// float, and to an output when it should be driven, so the semantics of
+// SetValue are unchanged.  Any bias options apply while the line is floating.
+// While floating the line reports as an input in its LineInfo.
+//
+// As the lines of a request share a direction, emulating the drive of
+// multiple lines requires a separate request for each line, so the lines are
+// requested individually if the drive must be emulated when the lines are
+// requested.  The drive of multiple lines requested together cannot be
+// emulated later, so reconfiguring such lines to a drive the kernel rejects
+// returns ErrEmulationConflict.
+//
+// Emulation requires Linux v5.5 or later.
+type EmulationOption struct {
+	force   bool
+	disable bool
+}
+
+func (o EmulationOption) applyLineOption(l *LineOptions) {
+	l.emulate = &o
+}
+
+// WithForcedDriveEmulation indicates that the OpenDrain and OpenSource options
+// always be emulated, even if supported by the kernel.
+//
+// Refer to EmulationOption for details.
+var WithForcedDriveEmulation = EmulationOption{force: true}
+
+// WithoutDriveEmulation indicates that the OpenDrain and OpenSource options
+// not be emulated, so any error from the kernel rejecting them is returned.
+var WithoutDriveEmulation = EmulationOption{disable: true}
+
+// PollOption indicates that edges be detected by polling the lines.
+type PollOption struct {
+	interval time.Duration
+}
+
+func (o PollOption) applyLineOption(l *LineOptions) {
+	l.poll = &o
+}
+
+// the interval used if WithPolledEdges is passed a non-positive interval.
+const defaultPollInterval = 10 * time.Millisecond
+
+// WithPolledEdges indicates that the edges requested by the WithFallingEdge,
+// WithRisingEdge or WithBothEdges options be detected by sampling the lines at
+// the given interval, rather than by the kernel.
+//
+// This is intended for chips that cannot provide edge interrupts, such as
+// some GPIO expanders.  The events are delivered to the event handler as per
+// kernel events, but are timestamped when the edge is detected and any pulses
+// shorter than the interval may be missed.
+//
+// If the interval is not positive then a default of 10ms is used.
+func WithPolledEdges(interval time.Duration) PollOption {
+	return PollOption{interval}
+}
+
+// LiveInfoOption indicates that the info for requested lines be kept current.
+type LiveInfoOption struct{}
+
+func (o LiveInfoOption) applyLineOption(l *LineOptions) {
+	l.liveInfo = true
+}
+
+// WithLiveInfo indicates that the info returned by Info for the requested
+// lines be kept current by watching for changes to the line info, rather than
+// being read from the chip on first use and after each Reconfigure.
+//
+// Requires Linux v5.7 or later.
+var WithLiveInfo = LiveInfoOption{}
+
+// Validate checks that the options are consistent, and are supported for a
+// request of the given number of lines.
+//
+// Options are applied in order, and later options override earlier options.
+// Validate rejects combinations where that override leaves the options in a
+// contradictory state, such as an edge detection option followed by an
+// output option, which would leave the event handler with no events.
+func (lo LineOptions) Validate(lines int) error {
+	if len(lo.InitialValues) > lines {
+		return ErrTooManyValues
+	}
+	output := lo.HandleFlags.IsOutput()
+	if lo.eh != nil && (lo.EventFlags == 0 || output) {
+		return ErrEdgeConflict
+	}
+	if lo.poll != nil && lo.eh == nil {
+		return ErrPollConflict
+	}
+	if (lo.HandleFlags.IsOpenDrain() || lo.HandleFlags.IsOpenSource()) && !output {
+		return ErrDriveConflict
+	}
+	if lo.eh == nil && !output && !lo.HandleFlags.IsInput() &&
+		(lo.HandleFlags.IsBiasDisable() ||
+			lo.HandleFlags.IsPullDown() ||
+			lo.HandleFlags.IsPullUp()) {
+		return ErrBiasConflict
+	}
+	if lo.closePolicy != nil {
+		if lo.eh != nil {
+			return ErrClosePolicyConflict
+		}
+		plo := LineOptions{
+			HandleFlags:   lo.HandleFlags,
+			InitialValues: lo.InitialValues,
+		}
+		for _, c := range lo.closePolicy {
+			c.applyLineConfig(&plo)
+		}
+		return plo.Validate(lines)
+	}
+	return nil
+}
+
+// String renders the effective configuration described by the options.
+func (lo LineOptions) String() string {
+	f := lo.HandleFlags
+	dirn := "as-is"
+	switch {
+	case lo.eh != nil || f.IsInput():
+		dirn = "input"
+	case f.IsOutput():
+		dirn = "output"
+	}
+	level := "active-high"
+	if f.IsActiveLow() {
+		level = "active-low"
+	}
+	drive := "push-pull"
+	switch {
+	case f.IsOpenDrain():
+		drive = "open-drain"
+	case f.IsOpenSource():
+		drive = "open-source"
+	}
+	bias := "as-is"
+	switch {
+	case f.IsBiasDisable():
+		bias = "disabled"
+	case f.IsPullDown():
+		bias = "pull-down"
+	case f.IsPullUp():
+		bias = "pull-up"
+	}
+	edge := "none"
+	switch lo.EventFlags {
+	case uapi.EventRequestRisingEdge:
+		edge = "rising"
+	case uapi.EventRequestFallingEdge:
+		edge = "falling"
+	case uapi.EventRequestBothEdges:
+		edge = "both"
+	}
+	s := fmt.Sprintf("consumer=%s direction=%s level=%s", lo.consumer, dirn, level)
+	if dirn == "output" {
+		s += fmt.Sprintf(" drive=%s values=%v", drive, lo.InitialValues)
+	}
+	s += fmt.Sprintf(" bias=%s", bias)
+	if dirn == "input" {
+		s += fmt.Sprintf(" edge=%s", edge)
+		if lo.poll != nil && lo.eh != nil {
+			interval := lo.poll.interval
+			if interval <= 0 {
+				interval = defaultPollInterval
+			}
+			s += fmt.Sprintf(" poll=%s", interval)
+		}
+	}
+	return s
+}
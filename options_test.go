@@ -121,7 +121,7 @@ func testLineDirectionReconfigure(t *testing.T, createOption gpiod.LineOption,
 	reconfigOption gpiod.LineConfig, info gpiod.LineInfo) {
 
 	tf := func(t *testing.T) {
-		requireKernel(t, setConfigKernel)
+		requireCapability(t, gpiod.CapSetConfig)
 
 		c := getChip(t)
 		defer c.Close()
@@ -287,7 +287,7 @@ func testLineLevelReconfigure(t *testing.T, createOption gpiod.LineOption,
 	reconfigOption gpiod.LineConfig, info gpiod.LineInfo, activeLevel int) {
 
 	tf := func(t *testing.T) {
-		requireKernel(t, setConfigKernel)
+		requireCapability(t, gpiod.CapSetConfig)
 
 		c := getChip(t)
 		defer c.Close()
@@ -358,7 +358,7 @@ func testLineDriveReconfigure(t *testing.T, createOption gpiod.LineOption,
 	reconfigOption gpiod.LineConfig, info gpiod.LineInfo, values ...int) {
 
 	tf := func(t *testing.T) {
-		requireKernel(t, setConfigKernel)
+		requireCapability(t, gpiod.CapSetConfig)
 
 		c := getChip(t)
 		defer c.Close()
@@ -410,7 +410,7 @@ func testChipBiasOption(t *testing.T, option gpiod.ChipOption,
 	info gpiod.LineInfo, expval int) {
 
 	tf := func(t *testing.T) {
-		requireKernel(t, biasKernel)
+		requireCapability(t, gpiod.CapBias)
 
 		c, err := gpiod.NewChip(platform.Devpath(), option)
 		assert.Nil(t, err)
@@ -442,7 +442,7 @@ func testLineBiasOption(t *testing.T, option gpiod.LineOption,
 	info gpiod.LineInfo, expval int) {
 
 	tf := func(t *testing.T) {
-		requireKernel(t, biasKernel)
+		requireCapability(t, gpiod.CapBias)
 
 		c := getChip(t)
 		defer c.Close()
@@ -470,7 +470,7 @@ func testLineBiasReconfigure(t *testing.T, createOption gpiod.LineOption,
 	reconfigOption gpiod.LineConfig, info gpiod.LineInfo, expval int) {
 
 	tf := func(t *testing.T) {
-		requireKernel(t, setConfigKernel)
+		requireCapability(t, gpiod.CapSetConfig)
 
 		c := getChip(t)
 		defer c.Close()
@@ -616,3 +616,394 @@ func clearEvents(ch <-chan gpiod.LineEvent) {
 	case <-time.After(20 * time.Millisecond):
 	}
 }
+
+func TestLineOptionsValidate(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	eh := func(gpiod.LineEvent) {}
+	patterns := []struct {
+		name    string
+		options []gpiod.LineOption
+		err     error
+	}{
+		{"default", nil, nil},
+		{"input", []gpiod.LineOption{gpiod.AsInput, gpiod.WithPullUp}, nil},
+		{"output", []gpiod.LineOption{gpiod.AsOutput(1, 0), gpiod.AsOpenDrain}, nil},
+		{"edge", []gpiod.LineOption{gpiod.WithPullDown, gpiod.WithBothEdges(eh)}, nil},
+		{"output overrides input", []gpiod.LineOption{gpiod.AsInput, gpiod.AsOutput(1)}, nil},
+		{"edge overrides output", []gpiod.LineOption{gpiod.AsOutput(1), gpiod.WithRisingEdge(eh)}, nil},
+		{"edge overrides drive", []gpiod.LineOption{gpiod.AsOpenDrain, gpiod.WithRisingEdge(eh)}, nil},
+		{"too many values", []gpiod.LineOption{gpiod.AsOutput(1, 0, 1)}, gpiod.ErrTooManyValues},
+		{"output after edge",
+			[]gpiod.LineOption{gpiod.WithBothEdges(eh), gpiod.AsOutput(1)},
+			gpiod.ErrEdgeConflict},
+		{"drive after edge",
+			[]gpiod.LineOption{gpiod.WithBothEdges(eh), gpiod.AsOpenDrain},
+			gpiod.ErrEdgeConflict},
+		{"input after drive",
+			[]gpiod.LineOption{gpiod.AsOpenSource, gpiod.AsInput},
+			nil},
+		{"as-is after drive",
+			[]gpiod.LineOption{gpiod.AsOpenSource, gpiod.AsIs},
+			gpiod.ErrDriveConflict},
+		{"bias with as-is",
+			[]gpiod.LineOption{gpiod.WithPullUp},
+			gpiod.ErrBiasConflict},
+		{"as-is after input with bias",
+			[]gpiod.LineOption{gpiod.AsInput, gpiod.WithPullUp, gpiod.AsIs},
+			gpiod.ErrBiasConflict},
+		{"poll without edge",
+			[]gpiod.LineOption{gpiod.AsInput, gpiod.WithPolledEdges(time.Millisecond)},
+			gpiod.ErrPollConflict},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			lo := c.LineOptions(p.options...)
+			err := lo.Validate(2)
+			assert.Equal(t, p.err, err)
+			if err == nil {
+				return
+			}
+			ll, err := c.RequestLines(platform.FloatingLines(), p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, ll)
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestLineOptionsString(t *testing.T) {
+	c, err := gpiod.NewChip(platform.Devpath(),
+		gpiod.WithConsumer("gpiod-test"), gpiod.AsActiveLow)
+	require.Nil(t, err)
+	require.NotNil(t, c)
+	defer c.Close()
+
+	eh := func(gpiod.LineEvent) {}
+	patterns := []struct {
+		name    string
+		options []gpiod.LineOption
+		str     string
+	}{
+		{"default", nil,
+			"consumer=gpiod-test direction=as-is level=active-low bias=as-is"},
+		{"input",
+			[]gpiod.LineOption{gpiod.AsInput, gpiod.WithPullUp, gpiod.AsActiveHigh},
+			"consumer=gpiod-test direction=input level=active-high bias=pull-up edge=none"},
+		{"output",
+			[]gpiod.LineOption{gpiod.WithConsumer("out"), gpiod.AsOutput(1, 0), gpiod.AsOpenDrain},
+			"consumer=out direction=output level=active-low drive=open-drain values=[1 0] bias=as-is"},
+		{"edge",
+			[]gpiod.LineOption{gpiod.WithBiasDisable, gpiod.WithFallingEdge(eh)},
+			"consumer=gpiod-test direction=input level=active-low bias=disabled edge=falling"},
+		{"polled edge",
+			[]gpiod.LineOption{gpiod.WithBothEdges(eh), gpiod.WithPolledEdges(0)},
+			"consumer=gpiod-test direction=input level=active-low bias=as-is edge=both poll=10ms"},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			lo := c.LineOptions(p.options...)
+			assert.Equal(t, p.str, lo.String())
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestReconfigureValidate(t *testing.T) {
+	requireCapability(t, gpiod.CapSetConfig)
+
+	c := getChip(t)
+	defer c.Close()
+
+	ll, err := c.RequestLines(platform.FloatingLines(), gpiod.AsInput)
+	require.Nil(t, err)
+	require.NotNil(t, ll)
+	defer ll.Close()
+	err = ll.Reconfigure(gpiod.AsOutput(1, 0, 1))
+	assert.Equal(t, gpiod.ErrTooManyValues, err)
+	inf, err := c.LineInfo(platform.FloatingLines()[0])
+	assert.Nil(t, err)
+	assert.False(t, inf.IsOut)
+}
+
+func TestWithVerifiedWrites(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	settle := 20 * time.Millisecond
+	l, err := c.RequestLine(platform.FloatingLines()[0],
+		gpiod.AsOutput(0), gpiod.WithVerifiedWrites(settle))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	start := time.Now()
+	err = l.SetValue(1)
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= settle)
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	l.Close()
+
+	ll, err := c.RequestLines(platform.FloatingLines(),
+		gpiod.AsOutput(0, 0), gpiod.WithVerifiedWrites(0))
+	require.Nil(t, err)
+	require.NotNil(t, ll)
+	err = ll.SetValues([]int{1, 0})
+	assert.Nil(t, err)
+	err = ll.SetValues([]int{0, 1})
+	assert.Nil(t, err)
+	ll.Close()
+
+	// inputs are not writable, so nothing to verify
+	l, err = c.RequestLine(platform.FloatingLines()[0],
+		gpiod.AsInput, gpiod.WithVerifiedWrites(0))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	err = l.SetValue(1)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+	l.Close()
+}
+
+func TestErrorMismatch(t *testing.T) {
+	err := gpiod.ErrorMismatch{[]int{3, 5}}
+	assert.Equal(t, "readback mismatch on offsets [3 5]", err.Error())
+}
+
+func TestWithForcedDriveEmulation(t *testing.T) {
+	requireCapability(t, gpiod.CapSetConfig)
+
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.FloatingLines()[0]
+
+	isOut := func() bool {
+		t.Helper()
+		inf, err := c.LineInfo(offset)
+		assert.Nil(t, err)
+		return inf.IsOut
+	}
+
+	// open drain - floats high
+	l, err := c.RequestLine(offset, gpiod.AsOutput(1), gpiod.AsOpenDrain,
+		gpiod.WithForcedDriveEmulation)
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	assert.False(t, isOut())
+	err = l.SetValue(0)
+	assert.Nil(t, err)
+	assert.True(t, isOut())
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+	err = l.SetValue(1)
+	assert.Nil(t, err)
+	assert.False(t, isOut())
+
+	// open source - floats low
+	err = l.Reconfigure(gpiod.AsOpenSource)
+	assert.Nil(t, err)
+	assert.True(t, isOut())
+	v, err = l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	err = l.SetValue(0)
+	assert.Nil(t, err)
+	assert.False(t, isOut())
+
+	// active low inverts the level
+	err = l.Reconfigure(gpiod.AsActiveLow)
+	assert.Nil(t, err)
+	assert.True(t, isOut())
+
+	// push-pull ends emulation
+	err = l.Reconfigure(gpiod.AsPushPull, gpiod.AsOutput(0))
+	assert.Nil(t, err)
+	assert.True(t, isOut())
+	err = l.SetValue(1)
+	assert.Nil(t, err)
+	assert.True(t, isOut())
+
+	// input ends emulation
+	err = l.Reconfigure(gpiod.AsInput)
+	assert.Nil(t, err)
+	assert.False(t, isOut())
+	err = l.SetValue(1)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+	l.Close()
+
+}
+
+func TestWithForcedDriveEmulationToggle(t *testing.T) {
+	requireCapability(t, gpiod.CapSetConfig)
+
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.FloatingLines()[0]
+
+	patterns := []struct {
+		name  string
+		drive gpiod.LineOption
+		// the value for which the line floats, so is an input.
+		floats int
+	}{
+		{"open-drain", gpiod.AsOpenDrain, 1},
+		{"open-source", gpiod.AsOpenSource, 0},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			l, err := c.RequestLine(offset, gpiod.AsOutput(p.floats), p.drive,
+				gpiod.WithForcedDriveEmulation)
+			require.Nil(t, err)
+			require.NotNil(t, l)
+			defer l.Close()
+			for i, v := range []int{0, 1, 1, 0, 0, 1, 0, 1} {
+				err = l.SetValue(v)
+				assert.Nil(t, err, "step %d", i)
+				inf, err := c.LineInfo(offset)
+				assert.Nil(t, err)
+				assert.Equal(t, v != p.floats, inf.IsOut, "step %d", i)
+				if v != p.floats {
+					rv, err := l.Value()
+					assert.Nil(t, err)
+					assert.Equal(t, v, rv, "step %d", i)
+				}
+			}
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestWithForcedDriveEmulationLines(t *testing.T) {
+	requireCapability(t, gpiod.CapSetConfig)
+
+	c := getChip(t)
+	defer c.Close()
+	offsets := platform.FloatingLines()[:2]
+
+	isOut := func() []bool {
+		t.Helper()
+		oo := make([]bool, len(offsets))
+		for i, o := range offsets {
+			inf, err := c.LineInfo(o)
+			assert.Nil(t, err)
+			oo[i] = inf.IsOut
+		}
+		return oo
+	}
+
+	// each line switches direction independently.
+	ll, err := c.RequestLines(offsets, gpiod.AsOutput(1, 0), gpiod.AsOpenDrain,
+		gpiod.WithForcedDriveEmulation)
+	require.Nil(t, err)
+	require.NotNil(t, ll)
+	assert.Equal(t, []bool{false, true}, isOut())
+	err = ll.SetValues([]int{0, 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false}, isOut())
+	vv := []int{1, 1}
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, vv[0])
+
+	// push-pull ends emulation
+	err = ll.Reconfigure(gpiod.AsPushPull)
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, true}, isOut())
+	ll.Close()
+
+	// lines requested together cannot be split later.
+	ll, err = c.RequestLines(offsets, gpiod.AsOutput(1, 0),
+		gpiod.WithForcedDriveEmulation)
+	require.Nil(t, err)
+	require.NotNil(t, ll)
+	defer ll.Close()
+	err = ll.Reconfigure(gpiod.AsOpenDrain)
+	assert.Equal(t, gpiod.ErrEmulationConflict, err)
+	assert.Equal(t, []bool{true, true}, isOut())
+}
+
+func TestDriveEmulationDefault(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.FloatingLines()[0]
+
+	// mockup supports open drain, so no emulation.
+	l, err := c.RequestLine(offset, gpiod.AsOutput(1), gpiod.AsOpenDrain)
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	inf, err := c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.True(t, inf.IsOut)
+	assert.True(t, inf.OpenDrain)
+}
+
+func TestWithoutDriveEmulation(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.FloatingLines()[0]
+
+	// the last emulation option applies.
+	l, err := c.RequestLine(offset, gpiod.AsOutput(1), gpiod.AsOpenDrain,
+		gpiod.WithForcedDriveEmulation, gpiod.WithoutDriveEmulation)
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	inf, err := c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.True(t, inf.IsOut)
+	assert.True(t, inf.OpenDrain)
+}
+
+func TestWithPolledEdges(t *testing.T) {
+	platform.TriggerIntr(0)
+	c := getChip(t)
+	defer c.Close()
+
+	ich := make(chan gpiod.LineEvent, 3)
+	lines := append(platform.FloatingLines(), platform.IntrLine())
+	r, err := c.RequestLines(lines,
+		gpiod.WithBothEdges(func(evt gpiod.LineEvent) {
+			ich <- evt
+		}),
+		gpiod.WithPolledEdges(time.Millisecond))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	// requested as a handle, not as events
+	inf, err := c.LineInfo(platform.IntrLine())
+	assert.Nil(t, err)
+	assert.True(t, inf.Requested)
+	assert.False(t, inf.IsOut)
+	err = r.Reconfigure(gpiod.AsActiveLow)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+
+	waitNoEvent(t, ich)
+	platform.TriggerIntr(1)
+	waitEvent(t, ich, gpiod.LineEventRisingEdge)
+	platform.TriggerIntr(0)
+	waitEvent(t, ich, gpiod.LineEventFallingEdge)
+	waitNoEvent(t, ich)
+	err = r.Close()
+	assert.Nil(t, err)
+	inf, err = c.LineInfo(platform.IntrLine())
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+
+	// rising only
+	l, err := c.RequestLine(platform.IntrLine(),
+		gpiod.WithRisingEdge(func(evt gpiod.LineEvent) {
+			ich <- evt
+		}),
+		gpiod.WithPolledEdges(time.Millisecond))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	platform.TriggerIntr(1)
+	waitEvent(t, ich, gpiod.LineEventRisingEdge)
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	platform.TriggerIntr(0)
+	waitNoEvent(t, ich)
+}
@@ -0,0 +1,217 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LineOwner identifies a process holding a request for lines.
+type LineOwner struct {
+	// The ID of the owning process.
+	Pid int
+
+	// The command line of the owning process.
+	Cmdline []string
+
+	// The fd of the request within the owning process.
+	Fd int
+
+	// The name of the chip containing the requested lines.
+	//
+	// This is empty if the chip cannot be determined.
+	Chip string
+
+	// The offsets of the requested lines.
+	//
+	// This is nil if the lines cannot be determined.
+	Offsets []int
+}
+
+// LineOwners returns the owners of all line requests held by processes
+// visible to the caller.
+//
+// The requests are found by scanning the fds of each process in /proc, so
+// processes owned by other users are only visible to root.
+//
+// The chip and offsets of a request are only reported by the kernel for
+// requests made using the v2 uAPI, on Linux v6.7 or later.  For other
+// requests, including all requests made by this package, the process holding
+// the request can be identified, but not the lines it holds, so the Chip and
+// Offsets are left empty.
+func LineOwners() ([]LineOwner, error) {
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	oo := []LineOwner(nil)
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil {
+			continue
+		}
+		// errors are ignored as the process may have exited, or not be
+		// accessible to the caller.
+		oo = append(oo, processLineOwners(pid)...)
+	}
+	return oo, nil
+}
+
+// FindLineOwners returns the owners of line requests that may hold the line
+// on the named chip.
+//
+// If a request is known to hold the line then only the owner of that request
+// is returned.  Otherwise the owners of any requests whose lines cannot be
+// determined, which can be identified by their nil Offsets, are returned as
+// possible owners.  Refer to LineOwners for details.
+//
+// The possible owners are narrowed to the process identified by the consumer
+// of the line, if the line was requested with the default consumer, gpiod-<pid>.
+// No owners are returned if the line is not requested.
+func FindLineOwners(chip string, offset int) ([]LineOwner, error) {
+	c, err := NewChip(chip)
+	if err != nil {
+		return nil, err
+	}
+	li, err := c.LineInfo(offset)
+	c.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !li.Requested {
+		return nil, nil
+	}
+	oo, err := LineOwners()
+	if err != nil {
+		return nil, err
+	}
+	chip = c.Name
+	unknown := []LineOwner(nil)
+	for _, o := range oo {
+		if o.Offsets == nil {
+			unknown = append(unknown, o)
+			continue
+		}
+		if o.Chip != chip {
+			continue
+		}
+		for _, lo := range o.Offsets {
+			if lo == offset {
+				return []LineOwner{o}, nil
+			}
+		}
+	}
+	if pid, ok := consumerPid(li.Consumer); ok {
+		found := []LineOwner(nil)
+		for _, o := range unknown {
+			if o.Pid == pid {
+				found = append(found, o)
+			}
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return unknown, nil
+}
+
+// consumerPid returns the pid from a default consumer label, gpiod-<pid>.
+func consumerPid(consumer string) (int, bool) {
+	if !strings.HasPrefix(consumer, "gpiod-") {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(consumer[len("gpiod-"):])
+	if err != nil {
+		return 0, false
+	}
+	return pid, true
+}
+
+// processLineOwners returns the line requests held by a process.
+func processLineOwners(pid int) []LineOwner {
+	dir := "/proc/" + strconv.Itoa(pid)
+	fds, err := ioutil.ReadDir(dir + "/fd")
+	if err != nil {
+		return nil
+	}
+	oo := []LineOwner(nil)
+	var cmdline []string
+	for _, f := range fds {
+		fd, err := strconv.Atoi(f.Name())
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink(dir + "/fd/" + f.Name())
+		if err != nil {
+			continue
+		}
+		o := LineOwner{Pid: pid, Fd: fd}
+		switch target {
+		case lineInode:
+			o.Chip, o.Offsets = readFdinfo(dir + "/fdinfo/" + f.Name())
+		case handleInode, eventInode:
+		default:
+			continue
+		}
+		if cmdline == nil {
+			cmdline = readCmdline(dir + "/cmdline")
+		}
+		o.Cmdline = cmdline
+		oo = append(oo, o)
+	}
+	return oo
+}
+
+// readFdinfo reads the chip and offsets of a request from the fdinfo for the
+// request fd.
+func readFdinfo(path string) (chip string, offsets []int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		kv := strings.SplitN(s.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v := strings.TrimSpace(kv[1])
+		switch kv[0] {
+		case "gpio-chip":
+			chip = v
+		case "gpio-line":
+			if o, err := strconv.Atoi(v); err == nil {
+				offsets = append(offsets, o)
+			}
+		}
+	}
+	if len(chip) == 0 {
+		// kernel does not report the lines.
+		offsets = nil
+	}
+	return
+}
+
+// readCmdline reads the command line of a process.
+func readCmdline(path string) []string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil || len(b) == 0 {
+		return []string{}
+	}
+	args := []string{}
+	for _, arg := range bytes.Split(bytes.TrimRight(b, "\x00"), []byte{0}) {
+		args = append(args, string(arg))
+	}
+	return args
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+)
+
+func TestLineOwners(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	countOwners := func(oo []gpiod.LineOwner) int {
+		count := 0
+		for _, o := range oo {
+			if o.Pid == os.Getpid() {
+				assert.Equal(t, os.Args, o.Cmdline)
+				count++
+			}
+		}
+		return count
+	}
+
+	// none
+	oo, err := gpiod.LineOwners()
+	assert.Nil(t, err)
+	assert.Zero(t, countOwners(oo))
+
+	// handle request
+	offset := platform.FloatingLines()[0]
+	l, err := c.RequestLine(offset)
+	require.Nil(t, err)
+	oo, err = gpiod.LineOwners()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countOwners(oo))
+	// narrowed to this process by the default consumer
+	oo, err = gpiod.FindLineOwners(c.Name, offset)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countOwners(oo))
+	assert.Equal(t, len(oo), countOwners(oo))
+	l.Close()
+
+	// not requested
+	oo, err = gpiod.FindLineOwners(c.Name, offset)
+	assert.Nil(t, err)
+	assert.Empty(t, oo)
+
+	// custom consumer can't be narrowed
+	l, err = c.RequestLine(offset, gpiod.WithConsumer("owner-test"))
+	require.Nil(t, err)
+	oo, err = gpiod.FindLineOwners(c.Name, offset)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countOwners(oo))
+	l.Close()
+
+	// event request - one fd per line
+	ll, err := c.RequestLines(platform.FloatingLines(),
+		gpiod.WithBothEdges(func(gpiod.LineEvent) {}))
+	require.Nil(t, err)
+	oo, err = gpiod.LineOwners()
+	assert.Nil(t, err)
+	assert.Equal(t, len(platform.FloatingLines()), countOwners(oo))
+	ll.Close()
+
+	oo, err = gpiod.FindLineOwners(c.Name, offset)
+	assert.Nil(t, err)
+	assert.Empty(t, oo)
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// poller detects edges by periodically sampling the values of lines.
+type poller struct {
+	offsets []int
+
+	// the edges to report.
+	edge uapi.EventFlag
+
+	interval time.Duration
+
+	// reads the current values of the lines.
+	read func(*uapi.HandleData) error
+
+	// releases the resources used to read the lines.
+	release func()
+
+	// the values from the previous sample.
+	values uapi.HandleData
+}
+
+// newPollWatcher creates a watcher that samples the lines each time a timerfd
+// expires, and synthesises events for any edges detected.
+func newPollWatcher(chip string, p *poller, eh EventHandler) (*watcher, error) {
+	if p.interval <= 0 {
+		p.interval = defaultPollInterval
+	}
+	if err := p.read(&p.values); err != nil {
+		return nil, err
+	}
+	tfd, err := timerfdCreate()
+	if err != nil {
+		return nil, err
+	}
+	if err = timerfdSettime(tfd, p.interval); err != nil {
+		unix.Close(tfd)
+		return nil, err
+	}
+	w, err := startWatcher(&watcher{
+		chip:   chip,
+		evtfds: map[int]int{tfd: 0},
+		eh:     eh,
+		poll:   p,
+	})
+	if err != nil {
+		unix.Close(tfd)
+	}
+	return w, err
+}
+
+// getPolledRequest requests the lines as inputs, with edges detected by
+// polling.
+func (c *Chip) getPolledRequest(offsets []int, lo LineOptions) (uintptr, *watcher, error) {
+	plo := lo
+	plo.HandleFlags |= uapi.HandleRequestInput
+	fd, err := c.getHandleRequest(offsets, plo)
+	if err != nil {
+		return 0, nil, err
+	}
+	w, err := newPollWatcher(c.Name, newHandlePoller(fd, offsets, lo.EventFlags, lo.poll.interval), lo.eh)
+	if err != nil {
+		unix.Close(int(fd))
+		return 0, nil, err
+	}
+	return fd, w, nil
+}
+
+// newHandlePoller creates a poller that samples the lines of a handle
+// request, and which takes ownership of the request.
+func newHandlePoller(fd uintptr, offsets []int, edge uapi.EventFlag, interval time.Duration) *poller {
+	return &poller{
+		offsets:  offsets,
+		edge:     edge,
+		interval: interval,
+		read: func(vv *uapi.HandleData) error {
+			return uapi.GetLineValues(fd, vv)
+		},
+		release: func() {
+			unix.Close(int(fd))
+		},
+	}
+}
+
+// sample reads the lines after the timer expires, and returns the events for
+// any edges detected since the previous sample.
+func (p *poller) sample(chip string, tfd int) []LineEvent {
+	var expiries [8]byte
+	unix.Read(tfd, expiries[:])
+	var ts unix.Timespec
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts)
+	var vv uapi.HandleData
+	if err := p.read(&vv); err != nil {
+		return nil
+	}
+	evts := []LineEvent(nil)
+	for i, o := range p.offsets {
+		if vv[i] == p.values[i] {
+			continue
+		}
+		le := LineEvent{
+			Chip:      chip,
+			Offset:    o,
+			Timestamp: time.Duration(ts.Nano()),
+			Type:      LineEventFallingEdge,
+		}
+		if vv[i] != 0 {
+			le.Type = LineEventRisingEdge
+		}
+		if (le.Type == LineEventRisingEdge && p.edge.IsRisingEdge()) ||
+			(le.Type == LineEventFallingEdge && p.edge.IsFallingEdge()) {
+			evts = append(evts, le)
+		}
+	}
+	p.values = vv
+	return evts
+}
+
+// itimerspec mirrors struct itimerspec, which is not provided by x/sys/unix.
+type itimerspec struct {
+	interval unix.Timespec
+	value    unix.Timespec
+}
+
+func timerfdCreate() (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_TIMERFD_CREATE,
+		unix.CLOCK_MONOTONIC,
+		unix.O_CLOEXEC|unix.O_NONBLOCK,
+		0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(fd), nil
+}
+
+func timerfdSettime(fd int, interval time.Duration) error {
+	ts := unix.NsecToTimespec(int64(interval))
+	its := itimerspec{interval: ts, value: ts}
+	_, _, errno := unix.Syscall6(unix.SYS_TIMERFD_SETTIME,
+		uintptr(fd),
+		0,
+		uintptr(unsafe.Pointer(&its)),
+		0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot is the state of a chip, and all of its lines, at a point in time.
+//
+// Snapshots can be marshalled to JSON, for storage, or to text, in the format
+// of gpioinfo, and compared using DiffSnapshots.
+type Snapshot struct {
+	// The time the snapshot was taken.
+	Time time.Time `json:"time"`
+
+	// The system name for the chip.
+	Name string `json:"name"`
+
+	// The label of the chip.
+	Label string `json:"label"`
+
+	// The sysfs path of the device that provides the chip, if known.
+	Parent string `json:"parent,omitempty"`
+
+	// The device tree node of the chip, if known.
+	OfNode string `json:"of_node,omitempty"`
+
+	// The info for each line, indexed by offset.
+	Lines []LineInfo `json:"lines"`
+}
+
+// Snapshot returns the current state of the chip and all of its lines.
+func (c *Chip) Snapshot() (*Snapshot, error) {
+	s := Snapshot{
+		Time:   time.Now(),
+		Name:   c.Name,
+		Label:  c.Label,
+		Parent: c.Parent,
+		OfNode: c.OfNode,
+		Lines:  make([]LineInfo, c.lines),
+	}
+	for o := range s.Lines {
+		li, err := c.LineInfo(o)
+		if err != nil {
+			return nil, err
+		}
+		s.Lines[o] = li
+	}
+	return &s, nil
+}
+
+// snapshot has the same fields as Snapshot, but not its methods, so can be
+// marshalled to JSON without recursion.
+type snapshot Snapshot
+
+// MarshalJSON renders the snapshot as JSON.
+//
+// This is provided so the JSON encoding takes precedence over MarshalText.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshot(s))
+}
+
+// MarshalText renders the snapshot as text, in the format of gpioinfo.
+func (s Snapshot) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// String renders the snapshot as text, in the format of gpioinfo.
+func (s Snapshot) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %d lines:\n", s.Name, len(s.Lines))
+	for _, li := range s.Lines {
+		fmt.Fprintf(&b, "\t%s\n", li)
+	}
+	return b.String()
+}
+
+// String renders the line info as text, in the format of gpioinfo.
+func (li LineInfo) String() string {
+	name := li.Name
+	if len(name) == 0 {
+		name = "unnamed"
+	}
+	consumer := "unused"
+	if li.Requested {
+		consumer = li.Consumer
+		if len(consumer) == 0 {
+			consumer = "kernel"
+		}
+		if strings.Contains(consumer, " ") {
+			consumer = "\"" + consumer + "\""
+		}
+	}
+	dirn := "input"
+	if li.IsOut {
+		dirn = "output"
+	}
+	active := "active-high"
+	if li.ActiveLow {
+		active = "active-low"
+	}
+	flags := []string(nil)
+	if li.Requested {
+		flags = append(flags, "used")
+	}
+	if li.OpenDrain {
+		flags = append(flags, "open-drain")
+	}
+	if li.OpenSource {
+		flags = append(flags, "open-source")
+	}
+	if li.BiasDisable {
+		flags = append(flags, "bias-disabled")
+	}
+	if li.PullDown {
+		flags = append(flags, "pull-down")
+	}
+	if li.PullUp {
+		flags = append(flags, "pull-up")
+	}
+	switch {
+	case li.EdgeRising && li.EdgeFalling:
+		flags = append(flags, "both-edges")
+	case li.EdgeRising:
+		flags = append(flags, "rising-edge")
+	case li.EdgeFalling:
+		flags = append(flags, "falling-edge")
+	}
+	if li.DebouncePeriod != 0 {
+		flags = append(flags, "debounce="+li.DebouncePeriod.String())
+	}
+	if li.EventClock != EventClockMonotonic {
+		flags = append(flags, li.EventClock.String()+"-clock")
+	}
+	flstr := ""
+	if len(flags) > 0 {
+		flstr = "[" + strings.Join(flags, " ") + "]"
+	}
+	return fmt.Sprintf("line %3d:%12s%12s%8s%13s%s",
+		li.Offset, name, consumer, dirn, active, flstr)
+}
+
+// SnapshotChange describes a difference between two snapshots.
+type SnapshotChange struct {
+	// The offset of the line that changed, or -1 for attributes of the chip.
+	Offset int
+
+	// The attribute that changed.
+	Attr string
+
+	// The value of the attribute in the earlier snapshot.
+	//
+	// This is empty if the line is not present in the earlier snapshot.
+	From string
+
+	// The value of the attribute in the later snapshot.
+	//
+	// This is empty if the line is not present in the later snapshot.
+	To string
+}
+
+func (c SnapshotChange) String() string {
+	if c.Offset < 0 {
+		return fmt.Sprintf("%s: %q -> %q", c.Attr, c.From, c.To)
+	}
+	return fmt.Sprintf("line %d %s: %q -> %q", c.Offset, c.Attr, c.From, c.To)
+}
+
+// the attributes of line info compared by DiffSnapshots.
+var lineAttrs = []struct {
+	name  string
+	value func(LineInfo) string
+}{
+	{"name", func(li LineInfo) string { return li.Name }},
+	{"consumer", func(li LineInfo) string { return li.Consumer }},
+	{"requested", func(li LineInfo) string { return strconv.FormatBool(li.Requested) }},
+	{"is_out", func(li LineInfo) string { return strconv.FormatBool(li.IsOut) }},
+	{"active_low", func(li LineInfo) string { return strconv.FormatBool(li.ActiveLow) }},
+	{"open_drain", func(li LineInfo) string { return strconv.FormatBool(li.OpenDrain) }},
+	{"open_source", func(li LineInfo) string { return strconv.FormatBool(li.OpenSource) }},
+	{"bias_disable", func(li LineInfo) string { return strconv.FormatBool(li.BiasDisable) }},
+	{"pull_down", func(li LineInfo) string { return strconv.FormatBool(li.PullDown) }},
+	{"pull_up", func(li LineInfo) string { return strconv.FormatBool(li.PullUp) }},
+	{"edge_rising", func(li LineInfo) string { return strconv.FormatBool(li.EdgeRising) }},
+	{"edge_falling", func(li LineInfo) string { return strconv.FormatBool(li.EdgeFalling) }},
+	{"debounce_period", func(li LineInfo) string { return li.DebouncePeriod.String() }},
+	{"event_clock", func(li LineInfo) string { return li.EventClock.String() }},
+}
+
+// DiffSnapshots returns the differences between two snapshots of a chip,
+// ordered by offset, with changes to the chip itself first.
+//
+// Lines present in only one of the snapshots are reported as a change to the
+// "line" attribute, with the line info rendered as per LineInfo.String.
+//
+// The times of the snapshots are not compared.
+func DiffSnapshots(from, to *Snapshot) []SnapshotChange {
+	cc := []SnapshotChange(nil)
+	chipAttrs := []struct {
+		name     string
+		from, to string
+	}{
+		{"name", from.Name, to.Name},
+		{"label", from.Label, to.Label},
+		{"parent", from.Parent, to.Parent},
+		{"of_node", from.OfNode, to.OfNode},
+	}
+	for _, a := range chipAttrs {
+		if a.from != a.to {
+			cc = append(cc, SnapshotChange{-1, a.name, a.from, a.to})
+		}
+	}
+	lines := len(from.Lines)
+	if len(to.Lines) > lines {
+		lines = len(to.Lines)
+	}
+	for o := 0; o < lines; o++ {
+		switch {
+		case o >= len(from.Lines):
+			cc = append(cc, SnapshotChange{o, "line", "", to.Lines[o].String()})
+		case o >= len(to.Lines):
+			cc = append(cc, SnapshotChange{o, "line", from.Lines[o].String(), ""})
+		default:
+			for _, a := range lineAttrs {
+				f := a.value(from.Lines[o])
+				t := a.value(to.Lines[o])
+				if f != t {
+					cc = append(cc, SnapshotChange{o, a.name, f, t})
+				}
+			}
+		}
+	}
+	return cc
+}
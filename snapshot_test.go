@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+)
+
+func TestLineInfoString(t *testing.T) {
+	patterns := []struct {
+		name string
+		li   gpiod.LineInfo
+		str  string
+	}{
+		{"unused", gpiod.LineInfo{Offset: 3},
+			"line   3:     unnamed      unused   input  active-high"},
+		{"kernel", gpiod.LineInfo{Offset: 3, Name: "led", Requested: true},
+			"line   3:         led      kernel   input  active-high[used]"},
+		{"output", gpiod.LineInfo{
+			Offset:    12,
+			Name:      "led",
+			Consumer:  "my app",
+			Requested: true,
+			IsOut:     true,
+			ActiveLow: true,
+			OpenDrain: true,
+			PullUp:    true,
+		},
+			"line  12:         led    \"my app\"  output   active-low[used open-drain pull-up]"},
+		{"edges", gpiod.LineInfo{
+			Offset:         5,
+			Name:           "button",
+			Consumer:       "app",
+			Requested:      true,
+			EdgeRising:     true,
+			EdgeFalling:    true,
+			DebouncePeriod: 10 * time.Millisecond,
+			EventClock:     gpiod.EventClockRealtime,
+		},
+			"line   5:      button         app   input  active-high[used both-edges debounce=10ms realtime-clock]"},
+		{"rising", gpiod.LineInfo{Offset: 5, Requested: true, EdgeRising: true},
+			"line   5:     unnamed      kernel   input  active-high[used rising-edge]"},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			assert.Equal(t, p.str, p.li.String())
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	base := gpiod.Snapshot{
+		Name:  "gpiochip0",
+		Label: "mock",
+		Lines: []gpiod.LineInfo{
+			{Offset: 0, Name: "a"},
+			{Offset: 1, Name: "b"},
+		},
+	}
+	patterns := []struct {
+		name    string
+		to      gpiod.Snapshot
+		changes []gpiod.SnapshotChange
+	}{
+		{"none", base, nil},
+		{"time", gpiod.Snapshot{
+			Time:  time.Now(),
+			Name:  "gpiochip0",
+			Label: "mock",
+			Lines: base.Lines,
+		},
+			nil},
+		{"label", gpiod.Snapshot{
+			Name:  "gpiochip0",
+			Label: "other",
+			Lines: base.Lines,
+		},
+			[]gpiod.SnapshotChange{{Offset: -1, Attr: "label", From: "mock", To: "other"}}},
+		{"line", gpiod.Snapshot{
+			Name:  "gpiochip0",
+			Label: "mock",
+			Lines: []gpiod.LineInfo{
+				{Offset: 0, Name: "a"},
+				{Offset: 1, Name: "b", Requested: true, IsOut: true, Consumer: "app"},
+			},
+		},
+			[]gpiod.SnapshotChange{
+				{Offset: 1, Attr: "consumer", From: "", To: "app"},
+				{Offset: 1, Attr: "requested", From: "false", To: "true"},
+				{Offset: 1, Attr: "is_out", From: "false", To: "true"},
+			}},
+		{"added", gpiod.Snapshot{
+			Name:  "gpiochip0",
+			Label: "mock",
+			Lines: []gpiod.LineInfo{
+				{Offset: 0, Name: "a"},
+				{Offset: 1, Name: "b"},
+				{Offset: 2},
+			},
+		},
+			[]gpiod.SnapshotChange{
+				{Offset: 2, Attr: "line", From: "",
+					To: "line   2:     unnamed      unused   input  active-high"},
+			}},
+		{"removed", gpiod.Snapshot{
+			Name:  "gpiochip0",
+			Label: "mock",
+			Lines: base.Lines[:1],
+		},
+			[]gpiod.SnapshotChange{
+				{Offset: 1, Attr: "line",
+					From: "line   1:           b      unused   input  active-high",
+					To:   ""},
+			}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			changes := gpiod.DiffSnapshots(&base, &p.to)
+			assert.Equal(t, p.changes, changes)
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestSnapshotChangeString(t *testing.T) {
+	c := gpiod.SnapshotChange{Offset: -1, Attr: "label", From: "mock", To: "other"}
+	assert.Equal(t, "label: \"mock\" -> \"other\"", c.String())
+	c = gpiod.SnapshotChange{Offset: 4, Attr: "is_out", From: "false", To: "true"}
+	assert.Equal(t, "line 4 is_out: \"false\" -> \"true\"", c.String())
+}
+
+func TestSnapshotMarshal(t *testing.T) {
+	s := gpiod.Snapshot{
+		Time:  time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC),
+		Name:  "gpiochip0",
+		Label: "mock",
+		Lines: []gpiod.LineInfo{
+			{Offset: 0, Name: "a"},
+			{Offset: 1, Requested: true, Consumer: "app", IsOut: true},
+		},
+	}
+	b, err := json.Marshal(s)
+	require.Nil(t, err)
+	assert.Equal(t, `{"time":"2020-06-01T12:00:00Z","name":"gpiochip0","label":"mock",`+
+		`"lines":[{"offset":0,"name":"a"},`+
+		`{"offset":1,"consumer":"app","requested":true,"is_out":true}]}`,
+		string(b))
+	var s2 gpiod.Snapshot
+	err = json.Unmarshal(b, &s2)
+	require.Nil(t, err)
+	assert.Nil(t, gpiod.DiffSnapshots(&s, &s2))
+	assert.True(t, s.Time.Equal(s2.Time))
+
+	txt, err := s.MarshalText()
+	require.Nil(t, err)
+	assert.Equal(t, "gpiochip0 - 2 lines:\n"+
+		"\tline   0:           a      unused   input  active-high\n"+
+		"\tline   1:     unnamed         app  output  active-high[used]\n",
+		string(txt))
+}
+
+func TestChipSnapshot(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+	before, err := c.Snapshot()
+	require.Nil(t, err)
+	require.NotNil(t, before)
+	assert.Equal(t, c.Name, before.Name)
+	assert.Equal(t, c.Label, before.Label)
+	assert.Equal(t, c.Lines(), len(before.Lines))
+	for o, li := range before.Lines {
+		assert.Equal(t, o, li.Offset)
+	}
+
+	l, err := c.RequestLine(platform.OutLine(), gpiod.AsOutput(1))
+	require.Nil(t, err)
+	after, err := c.Snapshot()
+	assert.Nil(t, err)
+	l.Close()
+	changes := gpiod.DiffSnapshots(before, after)
+	require.NotEmpty(t, changes)
+	for _, change := range changes {
+		assert.Equal(t, platform.OutLine(), change.Offset)
+	}
+
+	c.Close()
+	_, err = c.Snapshot()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
@@ -0,0 +1,450 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// the root of the legacy sysfs GPIO interface.
+const sysfsGPIOPath = "/sys/class/gpio"
+
+// the consumer reported for lines exported via sysfs.
+const sysfsConsumer = "sysfs"
+
+const biasFlags = uapi.HandleRequestBiasDisable |
+	uapi.HandleRequestPullDown |
+	uapi.HandleRequestPullUp
+
+// sysfsChip is the backend for a chip accessed via the legacy sysfs GPIO
+// interface, for platforms where the GPIO character device is not available.
+type sysfsChip struct {
+	// the sysfs directory of the chip, e.g. /sys/class/gpio/gpiochip0
+	dir string
+
+	// the global number of the first line of the chip.
+	base int
+}
+
+// sysfsLines is the backend for lines exported via sysfs.
+type sysfsLines struct {
+	chip *sysfsChip
+
+	// the global numbers of the lines.
+	gpios []int
+
+	// the fds of the value files of the lines.
+	fds []int
+}
+
+// sysfsChipDir returns the sysfs directory of the named chip, if the chip is
+// available via sysfs.
+//
+// The name may be the full path of the directory, e.g.
+// /sys/class/gpio/gpiochip0, or just the name, e.g. gpiochip0.
+func sysfsChipDir(name string) (string, bool) {
+	dir := name
+	if !strings.HasPrefix(name, sysfsGPIOPath+"/") {
+		dir = sysfsGPIOPath + "/" + name
+	}
+	if _, err := os.Stat(dir + "/base"); err != nil {
+		return "", false
+	}
+	return dir, true
+}
+
+// sysfsChipNames returns the names of the chips available via sysfs.
+func sysfsChipNames() []string {
+	dd, err := filepath.Glob(sysfsGPIOPath + "/gpiochip*")
+	if err != nil {
+		return nil
+	}
+	cc := []string(nil)
+	for _, d := range dd {
+		if _, ok := sysfsChipDir(d); ok {
+			cc = append(cc, filepath.Base(d))
+		}
+	}
+	return cc
+}
+
+func newSysfsChip(dir string, co ChipOptions) (*Chip, error) {
+	base, err := readSysfsInt(dir + "/base")
+	if err != nil {
+		return nil, err
+	}
+	lines, err := readSysfsInt(dir + "/ngpio")
+	if err != nil {
+		return nil, err
+	}
+	label, _ := readSysfsAttr(dir + "/label")
+	c := Chip{
+		Name:    filepath.Base(dir),
+		Label:   label,
+		lines:   lines,
+		options: co,
+		sysfs:   &sysfsChip{dir: dir, base: base},
+	}
+	if len(c.Label) == 0 {
+		c.Label = "unknown"
+	}
+	if p, err := filepath.EvalSymlinks(dir + "/device"); err == nil {
+		c.Parent = p
+		if n, err := filepath.EvalSymlinks(p + "/of_node"); err == nil {
+			c.OfNode = trimOfNode(n)
+		}
+	}
+	return &c, nil
+}
+
+func (s *sysfsChip) gpioDir(gpio int) string {
+	return fmt.Sprintf("%s/gpio%d", sysfsGPIOPath, gpio)
+}
+
+// lineInfo returns the info for the line, as far as sysfs can provide it.
+//
+// Lines requested via the character device are not visible via sysfs, and
+// sysfs does not provide line names.
+func (s *sysfsChip) lineInfo(offset int) (LineInfo, error) {
+	info := LineInfo{Offset: offset}
+	dir := s.gpioDir(s.base + offset)
+	if _, err := os.Stat(dir + "/value"); err != nil {
+		if os.IsNotExist(err) {
+			return info, nil
+		}
+		return info, err
+	}
+	info.Requested = true
+	info.Consumer = sysfsConsumer
+	if d, err := readSysfsAttr(dir + "/direction"); err == nil {
+		info.IsOut = d == "out"
+	}
+	if al, err := readSysfsAttr(dir + "/active_low"); err == nil {
+		info.ActiveLow = al == "1"
+	}
+	if e, err := readSysfsAttr(dir + "/edge"); err == nil {
+		info.EdgeRising = e == "rising" || e == "both"
+		info.EdgeFalling = e == "falling" || e == "both"
+	}
+	return info, nil
+}
+
+// checkSysfs returns an error if the options include features that cannot be
+// provided via sysfs.
+func checkSysfs(lo LineOptions) error {
+	if lo.wait != nil {
+		return ErrorSysfsUnsupported{"waiting for lines"}
+	}
+	if lo.liveInfo {
+		return ErrorSysfsUnsupported{"watching line info"}
+	}
+	if err := checkSysfsFlags(lo.HandleFlags); err != nil {
+		return err
+	}
+	if lo.closePolicy != nil {
+		plo := LineOptions{HandleFlags: lo.HandleFlags}
+		for _, c := range lo.closePolicy {
+			c.applyLineConfig(&plo)
+		}
+		return checkSysfsFlags(plo.HandleFlags)
+	}
+	return nil
+}
+
+func checkSysfsFlags(flags uapi.HandleFlag) error {
+	if flags&biasFlags != 0 {
+		return ErrorSysfsUnsupported{"bias"}
+	}
+	if flags&driveFlags != 0 {
+		return ErrorSysfsUnsupported{"drive"}
+	}
+	return nil
+}
+
+// requestSysfsLines requests the lines by exporting them via sysfs.
+func (c *Chip) requestSysfsLines(offsets []int, lo LineOptions) (*Lines, error) {
+	if err := checkSysfs(lo); err != nil {
+		return nil, err
+	}
+	sl := &sysfsLines{chip: c.sysfs}
+	for _, o := range offsets {
+		fd, err := sl.export(c.sysfs.base + o)
+		if err != nil {
+			sl.closeFds()
+			sl.unexport()
+			return nil, err
+		}
+		sl.fds = append(sl.fds, fd)
+	}
+	ll := Lines{baseLine{
+		offsets:      append([]int(nil), offsets...),
+		chip:         c.Name,
+		flags:        lo.HandleFlags,
+		outputValues: lo.InitialValues,
+		verify:       lo.verify,
+		sysfs:        sl,
+	}}
+	err := sl.configure(lo.HandleFlags, lo.InitialValues)
+	if err == nil && lo.eh != nil && lo.poll != nil {
+		ll.isEvent = true
+		ll.w, err = newPollWatcher(c.Name, &poller{
+			offsets:  ll.offsets,
+			edge:     lo.EventFlags,
+			interval: lo.poll.interval,
+			read:     sl.values,
+			release:  sl.closeFds,
+		}, lo.eh)
+	} else if err == nil && lo.eh != nil {
+		ll.isEvent = true
+		err = sl.setEdge(lo.EventFlags)
+		if err == nil {
+			// a value file reports POLLPRI until it is first read, so read
+			// them to prevent a spurious event when the watcher starts.
+			var vv uapi.HandleData
+			err = sl.values(&vv)
+		}
+		if err == nil {
+			fds := make(map[int]int, len(offsets))
+			for i, fd := range sl.fds {
+				fds[fd] = offsets[i]
+			}
+			ll.w, err = newSysfsWatcher(c.Name, fds, lo.eh, lo.EventFlags)
+		}
+	}
+	if err != nil {
+		sl.closeFds()
+		sl.unexport()
+		return nil, err
+	}
+	return &ll, nil
+}
+
+// export exports the line and opens its value file.
+//
+// The export fails with unix.EBUSY if the line is already in use.
+func (s *sysfsLines) export(gpio int) (int, error) {
+	err := writeSysfsAttr(sysfsGPIOPath+"/export", strconv.Itoa(gpio))
+	if err != nil {
+		return 0, err
+	}
+	s.gpios = append(s.gpios, gpio)
+	value := s.chip.gpioDir(gpio) + "/value"
+	// udev may take a moment to apply permissions to the new files.
+	for i := 0; i < 100; i++ {
+		if unix.Access(value, unix.W_OK) == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fd, err := unix.Open(value, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err == unix.EACCES {
+		fd, err = unix.Open(value, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	}
+	return fd, err
+}
+
+func (s *sysfsLines) closeFds() {
+	for _, fd := range s.fds {
+		unix.Close(fd)
+	}
+}
+
+func (s *sysfsLines) unexport() {
+	for _, gpio := range s.gpios {
+		writeSysfsAttr(sysfsGPIOPath+"/unexport", strconv.Itoa(gpio))
+	}
+}
+
+// configure sets the direction and active level of the lines.
+func (s *sysfsLines) configure(flags uapi.HandleFlag, values []int) error {
+	if err := checkSysfsFlags(flags); err != nil {
+		return err
+	}
+	al := "0"
+	if flags.IsActiveLow() {
+		al = "1"
+	}
+	for i, gpio := range s.gpios {
+		dir := s.chip.gpioDir(gpio)
+		if err := writeSysfsAttr(dir+"/active_low", al); err != nil {
+			return err
+		}
+		var direction string
+		switch {
+		case flags.IsOutput():
+			// high and low set the physical level, not the active state.
+			high := flags.IsActiveLow()
+			if i < len(values) && values[i] != 0 {
+				high = !high
+			}
+			direction = "low"
+			if high {
+				direction = "high"
+			}
+		case flags.IsInput():
+			direction = "in"
+		default:
+			// as-is
+			continue
+		}
+		if err := writeSysfsAttr(dir+"/direction", direction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sysfsLines) setEdge(flags uapi.EventFlag) error {
+	edge := "both"
+	switch {
+	case flags.IsBothEdges():
+	case flags.IsRisingEdge():
+		edge = "rising"
+	case flags.IsFallingEdge():
+		edge = "falling"
+	}
+	for _, gpio := range s.gpios {
+		if err := writeSysfsAttr(s.chip.gpioDir(gpio)+"/edge", edge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sysfsLines) values(values *uapi.HandleData) error {
+	for i, fd := range s.fds {
+		v, err := readSysfsValue(fd)
+		if err != nil {
+			return err
+		}
+		values[i] = uint8(v)
+	}
+	return nil
+}
+
+func (s *sysfsLines) setValues(values uapi.HandleData) error {
+	for i, fd := range s.fds {
+		v := []byte{'0'}
+		if values[i] != 0 {
+			v[0] = '1'
+		}
+		if _, err := unix.Pwrite(fd, v, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// info returns the info for the lines.
+func (s *sysfsLines) info() ([]*LineInfo, error) {
+	info := make([]*LineInfo, len(s.gpios))
+	for i, gpio := range s.gpios {
+		inf, err := s.chip.lineInfo(gpio - s.chip.base)
+		if err != nil {
+			return nil, err
+		}
+		info[i] = &inf
+	}
+	return info, nil
+}
+
+// reconfigureSysfs updates the configuration of lines exported via sysfs.
+//
+// Assumes l is locked.
+func (l *baseLine) reconfigureSysfs(lo LineOptions) error {
+	if err := checkSysfs(lo); err != nil {
+		return err
+	}
+	err := l.sysfs.configure(lo.HandleFlags, lo.InitialValues)
+	if err == nil {
+		l.flags = lo.HandleFlags
+		l.outputValues = lo.InitialValues
+	}
+	return err
+}
+
+// readSysfsEvent determines the type of edge that triggered an event on the
+// value file of a line.
+//
+// The value is read even when the edge is implied, as reading the value
+// acknowledges the event.
+func readSysfsEvent(fd int, edge uapi.EventFlag) (LineEventType, error) {
+	v, err := readSysfsValue(fd)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case edge.IsBothEdges():
+		if v == 0 {
+			return LineEventFallingEdge, nil
+		}
+		return LineEventRisingEdge, nil
+	case edge.IsFallingEdge():
+		return LineEventFallingEdge, nil
+	}
+	return LineEventRisingEdge, nil
+}
+
+func readSysfsValue(fd int) (int, error) {
+	var buf [2]byte
+	if _, err := unix.Pread(fd, buf[:], 0); err != nil {
+		return 0, err
+	}
+	if buf[0] == '1' {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func readSysfsAttr(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	return strings.TrimSpace(string(b)), err
+}
+
+func readSysfsInt(path string) (int, error) {
+	s, err := readSysfsAttr(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(s)
+}
+
+func writeSysfsAttr(path, value string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte(value))
+	cerr := f.Close()
+	if err != nil {
+		if perr, ok := err.(*os.PathError); ok {
+			return perr.Err
+		}
+		return err
+	}
+	return cerr
+}
+
+// ErrorSysfsUnsupported indicates a feature cannot be provided by the legacy
+// sysfs GPIO interface.
+type ErrorSysfsUnsupported struct {
+	// The unsupported feature.
+	Feature string
+}
+
+func (e ErrorSysfsUnsupported) Error() string {
+	return "sysfs does not support " + e.Feature
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"golang.org/x/sys/unix"
+)
+
+// getSysfsChip opens the platform chip via the sysfs interface.
+func getSysfsChip(t *testing.T) *gpiod.Chip {
+	t.Helper()
+	dd, _ := filepath.Glob("/sys/class/gpio/gpiochip*")
+	for _, d := range dd {
+		label, err := ioutil.ReadFile(d + "/label")
+		if err != nil || strings.TrimSpace(string(label)) != platform.Label() {
+			continue
+		}
+		c, err := gpiod.NewChip(d)
+		require.Nil(t, err)
+		require.NotNil(t, c)
+		return c
+	}
+	t.Skip("platform chip not available via sysfs")
+	return nil
+}
+
+func TestSysfsChip(t *testing.T) {
+	c := getSysfsChip(t)
+	defer c.Close()
+	assert.Equal(t, platform.Label(), c.Label)
+	assert.Equal(t, platform.Lines(), c.Lines())
+
+	caps, err := c.Capabilities()
+	assert.Nil(t, err)
+	assert.False(t, caps.Has(gpiod.CapBias))
+
+	_, err = c.WatchLineInfo(0, func(gpiod.LineInfoChangeEvent) {})
+	assert.IsType(t, gpiod.ErrorSysfsUnsupported{}, err)
+
+	err = c.Close()
+	assert.Nil(t, err)
+	err = c.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestSysfsOutput(t *testing.T) {
+	c := getSysfsChip(t)
+	defer c.Close()
+	offset := platform.OutLine()
+
+	l, err := c.RequestLine(offset, gpiod.AsOutput(1))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+
+	// busy
+	l2, err := c.RequestLine(offset)
+	assert.Equal(t, unix.EBUSY, err)
+	assert.Nil(t, l2)
+
+	inf, err := l.Info()
+	assert.Nil(t, err)
+	assert.True(t, inf.Requested)
+	assert.True(t, inf.IsOut)
+	assert.Equal(t, "sysfs", inf.Consumer)
+	assert.Equal(t, 1, platform.ReadOut())
+
+	err = l.SetValue(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, platform.ReadOut())
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+
+	// active low inverts the physical level
+	err = l.Reconfigure(gpiod.AsActiveLow)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, platform.ReadOut())
+
+	err = l.Reconfigure(gpiod.WithPullUp)
+	assert.Equal(t, gpiod.ErrorSysfsUnsupported{Feature: "bias"}, err)
+
+	err = l.Close()
+	assert.Nil(t, err)
+	inf, err = c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+}
+
+func TestSysfsUnsupported(t *testing.T) {
+	c := getSysfsChip(t)
+	defer c.Close()
+	offset := platform.FloatingLines()[0]
+
+	patterns := []struct {
+		name    string
+		options []gpiod.LineOption
+		feature string
+	}{
+		{"bias", []gpiod.LineOption{gpiod.AsInput, gpiod.WithPullDown}, "bias"},
+		{"drive", []gpiod.LineOption{gpiod.AsOutput(1), gpiod.AsOpenDrain}, "drive"},
+		{"close policy", []gpiod.LineOption{gpiod.AsInput,
+			gpiod.OnClose(gpiod.WithPullUp)}, "bias"},
+		{"wait", []gpiod.LineOption{gpiod.WithWaitTimeout(0)}, "waiting for lines"},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			l, err := c.RequestLine(offset, p.options...)
+			assert.Nil(t, l)
+			assert.Equal(t, gpiod.ErrorSysfsUnsupported{Feature: p.feature}, err)
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestSysfsLineNames(t *testing.T) {
+	c := getSysfsChip(t)
+	defer c.Close()
+
+	xerr := gpiod.ErrorSysfsUnsupported{Feature: "line names"}
+	_, err := c.FindLine("")
+	assert.Equal(t, xerr, err)
+	_, err = c.FindLines("")
+	assert.Equal(t, xerr, err)
+	_, err = c.MatchLines("*")
+	assert.Equal(t, xerr, err)
+	_, err = c.DuplicateLineNames()
+	assert.Equal(t, xerr, err)
+}
+
+func TestSysfsEvents(t *testing.T) {
+	c := getSysfsChip(t)
+	defer c.Close()
+
+	platform.TriggerIntr(0)
+	ich := make(chan gpiod.LineEvent, 3)
+	l, err := c.RequestLine(platform.IntrLine(),
+		gpiod.WithBothEdges(func(evt gpiod.LineEvent) {
+			ich <- evt
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	waitNoEvent(t, ich)
+
+	platform.TriggerIntr(1)
+	waitEvent(t, ich, gpiod.LineEventRisingEdge)
+	platform.TriggerIntr(0)
+	waitEvent(t, ich, gpiod.LineEventFallingEdge)
+}
+
+func TestSysfsEventsSingleEdge(t *testing.T) {
+	c := getSysfsChip(t)
+	defer c.Close()
+
+	platform.TriggerIntr(0)
+	ich := make(chan gpiod.LineEvent, 3)
+	l, err := c.RequestLine(platform.IntrLine(),
+		gpiod.WithRisingEdge(func(evt gpiod.LineEvent) {
+			ich <- evt
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	waitNoEvent(t, ich)
+
+	platform.TriggerIntr(1)
+	waitEvent(t, ich, gpiod.LineEventRisingEdge)
+	platform.TriggerIntr(0)
+	waitNoEvent(t, ich)
+}
@@ -83,8 +83,7 @@ func TestAsIs(t *testing.T) {
 }
 
 func TestWatchIsolation(t *testing.T) {
-	requireKernel(t, infoWatchKernel)
-	requireMockup(t)
+	requireInfoWatch(t)
 	c, err := mock.Chip(0)
 	require.Nil(t, err)
 
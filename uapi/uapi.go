@@ -10,6 +10,7 @@ package uapi
 import (
 	"bytes"
 	"encoding/binary"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -154,6 +155,28 @@ func UnwatchLineInfo(fd uintptr, offset uint32) error {
 	return nil
 }
 
+// GetLineInfoV2 returns the LineInfoV2 for one line from the GPIO character
+// device.
+//
+// The fd is an open GPIO character device.
+// The offset is zero based.
+//
+// This is the only part of the v2 uAPI supported by this package, and is
+// used to read the attributes of a line not reported by GetLineInfo.
+// Requires Linux v5.10 or later.
+func GetLineInfoV2(fd uintptr, offset int) (LineInfoV2, error) {
+	var li LineInfoV2
+	li.Offset = uint32(offset)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL,
+		fd,
+		uintptr(getLineInfoV2Ioctl),
+		uintptr(unsafe.Pointer(&li)))
+	if errno != 0 {
+		return LineInfoV2{}, errno
+	}
+	return li, nil
+}
+
 // BytesToString is a helper function that converts strings stored in byte
 // arrays, as returned by GetChipInfo and GetLineInfo, into strings.
 func BytesToString(a []byte) string {
@@ -207,6 +230,7 @@ var (
 	setLineConfigIoctl   ioctl
 	watchLineInfoIoctl   ioctl
 	unwatchLineInfoIoctl ioctl
+	getLineInfoV2Ioctl   ioctl
 )
 
 // Size of name and consumer strings.
@@ -229,6 +253,8 @@ func init() {
 	setLineConfigIoctl = iorw(0xB4, 0x0a, unsafe.Sizeof(hc))
 	watchLineInfoIoctl = iorw(0xB4, 0x0b, unsafe.Sizeof(li))
 	unwatchLineInfoIoctl = iorw(0xB4, 0x0c, unsafe.Sizeof(li.Offset))
+	var liv2 LineInfoV2
+	getLineInfoV2Ioctl = iorw(0xB4, 0x05, unsafe.Sizeof(liv2))
 }
 
 // ChipInfo contains the details of a GPIO chip.
@@ -367,6 +393,160 @@ func (f LineFlag) IsPullUp() bool {
 	return f&LineFlagPullUp != 0
 }
 
+// Maximum number of attributes in a LineInfoV2.
+const lineAttributesMax = 10
+
+// LineInfoV2 contains the details of a single line of a GPIO chip, as
+// reported by the v2 uAPI.
+type LineInfoV2 struct {
+	// The system name for this line.
+	Name [nameSize]byte
+
+	// If requested, a string added by the requester to identify the
+	// owner of the request.
+	Consumer [nameSize]byte
+
+	// The offset of the line within the chip.
+	Offset uint32
+
+	// The number of attributes in Attrs.
+	NumAttrs uint32
+
+	// The line flags applied to this line.
+	Flags LineFlagV2
+
+	// Attributes of the line, such as the debounce period, that are not
+	// described by the flags.
+	Attrs [lineAttributesMax]LineAttribute
+
+	// reserved for future use.
+	_ [4]uint32
+}
+
+// DebouncePeriod returns the debounce period of the line, or zero if the line
+// is not debounced.
+func (li LineInfoV2) DebouncePeriod() time.Duration {
+	for i := 0; i < int(li.NumAttrs) && i < lineAttributesMax; i++ {
+		if li.Attrs[i].ID == LineAttributeIDDebounce {
+			return li.Attrs[i].DebouncePeriod()
+		}
+	}
+	return 0
+}
+
+// LineAttribute is an attribute of a line in a LineInfoV2.
+type LineAttribute struct {
+	// The type of attribute.
+	ID LineAttributeID
+
+	// reserved for future use.
+	_ uint32
+
+	// The value of the attribute, the interpretation of which depends on the
+	// ID.
+	Value uint64
+}
+
+// DebouncePeriod returns the value of a LineAttributeIDDebounce attribute.
+func (a LineAttribute) DebouncePeriod() time.Duration {
+	// the period is a u32 overlaying the start of the u64 value.
+	var b [8]byte
+	nativeEndian.PutUint64(b[:], a.Value)
+	return time.Duration(nativeEndian.Uint32(b[:4])) * time.Microsecond
+}
+
+// LineAttributeID identifies the type of a LineAttribute.
+type LineAttributeID uint32
+
+const (
+	// LineAttributeIDFlags indicates the attribute value contains line flags.
+	LineAttributeIDFlags LineAttributeID = iota + 1
+
+	// LineAttributeIDOutputValues indicates the attribute value contains
+	// output values.
+	LineAttributeIDOutputValues
+
+	// LineAttributeIDDebounce indicates the attribute value contains the
+	// debounce period in microseconds.
+	LineAttributeIDDebounce
+)
+
+// LineFlagV2 are the flags for a line in a LineInfoV2.
+type LineFlagV2 uint64
+
+const (
+	// LineFlagV2Used indicates that the line is already in use and not
+	// available for request.
+	LineFlagV2Used LineFlagV2 = 1 << iota
+
+	// LineFlagV2ActiveLow indicates that the line is active low.
+	LineFlagV2ActiveLow
+
+	// LineFlagV2Input indicates that the line is an input.
+	LineFlagV2Input
+
+	// LineFlagV2Output indicates that the line is an output.
+	LineFlagV2Output
+
+	// LineFlagV2EdgeRising indicates that the line detects rising edges.
+	LineFlagV2EdgeRising
+
+	// LineFlagV2EdgeFalling indicates that the line detects falling edges.
+	LineFlagV2EdgeFalling
+
+	// LineFlagV2OpenDrain indicates that the line is an open drain output.
+	LineFlagV2OpenDrain
+
+	// LineFlagV2OpenSource indicates that the line is an open source output.
+	LineFlagV2OpenSource
+
+	// LineFlagV2BiasPullUp indicates that the internal line pull up is
+	// enabled.
+	LineFlagV2BiasPullUp
+
+	// LineFlagV2BiasPullDown indicates that the internal line pull down is
+	// enabled.
+	LineFlagV2BiasPullDown
+
+	// LineFlagV2BiasDisabled indicates that the internal line bias is
+	// disabled.
+	LineFlagV2BiasDisabled
+
+	// LineFlagV2EventClockRealtime indicates that edge events are
+	// timestamped using CLOCK_REALTIME.
+	//
+	// Requires Linux v5.11 or later.
+	LineFlagV2EventClockRealtime
+
+	// LineFlagV2EventClockHTE indicates that edge events are timestamped
+	// using a hardware timestamp engine.
+	//
+	// Requires Linux v5.19 or later.
+	LineFlagV2EventClockHTE
+)
+
+// IsEdgeRising returns true if the line detects rising edges.
+func (f LineFlagV2) IsEdgeRising() bool {
+	return f&LineFlagV2EdgeRising != 0
+}
+
+// IsEdgeFalling returns true if the line detects falling edges.
+func (f LineFlagV2) IsEdgeFalling() bool {
+	return f&LineFlagV2EdgeFalling != 0
+}
+
+// IsEventClockRealtime returns true if edge events are timestamped using
+// CLOCK_REALTIME.
+func (f LineFlagV2) IsEventClockRealtime() bool {
+	return f&LineFlagV2EventClockRealtime != 0
+}
+
+// IsEventClockHTE returns true if edge events are timestamped using a
+// hardware timestamp engine.
+func (f LineFlagV2) IsEventClockHTE() bool {
+	return f&LineFlagV2EventClockHTE != 0
+}
+
 // HandleConfig is a request to change the config of an existing request.
 //
 // Can be applied to both handle and event requests.
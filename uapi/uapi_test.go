@@ -12,6 +12,7 @@ import (
 	"syscall"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,11 +35,6 @@ func TestMain(m *testing.M) {
 	os.Exit(rc)
 }
 
-var (
-	setConfigKernel = mockup.Semver{5, 5} // setLineConfig ioctl added
-	infoWatchKernel = mockup.Semver{5, 7} // watchLineInfo ioctl added
-)
-
 func reloadMockup() {
 	if mock != nil {
 		mock.Close()
@@ -112,6 +108,26 @@ func TestGetLineInfo(t *testing.T) {
 	assert.Equal(t, lix, li)
 }
 
+func TestGetLineInfoV2(t *testing.T) {
+	requireUapiV2(t)
+	c, err := mock.Chip(0)
+	require.Nil(t, err)
+	f, err := os.Open(c.DevPath)
+	require.Nil(t, err)
+	defer f.Close()
+	li, err := uapi.GetLineInfoV2(f.Fd(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), li.Offset)
+	assert.Equal(t, fmt.Sprintf("%s-%d", c.Label, 1), uapi.BytesToString(li.Name[:]))
+	assert.Equal(t, uapi.LineFlagV2Input, li.Flags)
+	assert.Zero(t, li.DebouncePeriod())
+
+	// badfd
+	li, err = uapi.GetLineInfoV2(0, 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, uapi.LineInfoV2{}, li)
+}
+
 func TestGetLineEvent(t *testing.T) {
 	requireMockup(t)
 	patterns := []struct {
@@ -1148,8 +1164,7 @@ func TestSetLineHandleConfig(t *testing.T) {
 }
 
 func TestSetLineEventConfig(t *testing.T) {
-	requireKernel(t, setConfigKernel)
-	requireMockup(t)
+	requireSetConfig(t)
 	patterns := []struct {
 		name        string
 		cnum        int
@@ -1230,8 +1245,7 @@ func TestSetLineEventConfig(t *testing.T) {
 func TestWatchLineInfo(t *testing.T) {
 	// also covers ReadLineInfoChanged
 
-	requireKernel(t, infoWatchKernel)
-	requireMockup(t)
+	requireInfoWatch(t)
 	c, err := mock.Chip(0)
 	require.Nil(t, err)
 
@@ -1317,8 +1331,7 @@ func TestWatchLineInfo(t *testing.T) {
 }
 
 func TestUnwatchLineInfo(t *testing.T) {
-	requireKernel(t, infoWatchKernel)
-	requireMockup(t)
+	requireInfoWatch(t)
 	c, err := mock.Chip(0)
 	require.Nil(t, err)
 
@@ -1466,6 +1479,37 @@ func TestLineFlags(t *testing.T) {
 	assert.False(t, uapi.LineFlagBiasDisable.IsPullDown())
 }
 
+func TestLineFlagsV2(t *testing.T) {
+	assert.False(t, uapi.LineFlagV2(0).IsEdgeRising())
+	assert.False(t, uapi.LineFlagV2(0).IsEdgeFalling())
+	assert.False(t, uapi.LineFlagV2(0).IsEventClockRealtime())
+	assert.False(t, uapi.LineFlagV2(0).IsEventClockHTE())
+	assert.True(t, uapi.LineFlagV2EdgeRising.IsEdgeRising())
+	assert.False(t, uapi.LineFlagV2EdgeRising.IsEdgeFalling())
+	assert.True(t, uapi.LineFlagV2EdgeFalling.IsEdgeFalling())
+	assert.False(t, uapi.LineFlagV2EdgeFalling.IsEdgeRising())
+	assert.True(t, uapi.LineFlagV2EventClockRealtime.IsEventClockRealtime())
+	assert.False(t, uapi.LineFlagV2EventClockRealtime.IsEventClockHTE())
+	assert.True(t, uapi.LineFlagV2EventClockHTE.IsEventClockHTE())
+	assert.False(t, uapi.LineFlagV2EventClockHTE.IsEventClockRealtime())
+}
+
+func TestLineInfoV2DebouncePeriod(t *testing.T) {
+	li := uapi.LineInfoV2{}
+	assert.Zero(t, li.DebouncePeriod())
+
+	// the period is the u32 at the start of the value.
+	var b [8]byte
+	*(*uint32)(unsafe.Pointer(&b[0])) = 10000
+	v := *(*uint64)(unsafe.Pointer(&b[0]))
+	li.Attrs[1] = uapi.LineAttribute{ID: uapi.LineAttributeIDDebounce, Value: v}
+	// beyond NumAttrs
+	li.NumAttrs = 1
+	assert.Zero(t, li.DebouncePeriod())
+	li.NumAttrs = 2
+	assert.Equal(t, 10*time.Millisecond, li.DebouncePeriod())
+}
+
 func TestHandleFlags(t *testing.T) {
 	assert.False(t, uapi.HandleFlag(0).IsInput())
 	assert.False(t, uapi.HandleFlag(0).IsOutput())
@@ -1538,8 +1582,61 @@ func lineFromHandle(hf uapi.HandleFlag) uapi.LineFlag {
 	return lf
 }
 
-func requireKernel(t *testing.T, min mockup.Semver) {
-	if err := mockup.CheckKernelVersion(min); err != nil {
-		t.Skip(err)
+// requireSetConfig skips the test unless the kernel supports reconfiguring
+// requested lines.
+func requireSetConfig(t *testing.T) {
+	t.Helper()
+	f := openProbeChip(t)
+	defer f.Close()
+	hr := uapi.HandleRequest{Lines: 1, Flags: uapi.HandleRequestInput}
+	err := uapi.GetLineHandle(f.Fd(), &hr)
+	require.Nil(t, err)
+	defer unix.Close(int(hr.Fd))
+	hc := uapi.HandleConfig{Flags: uapi.HandleRequestInput}
+	err = uapi.SetLineConfig(uintptr(hr.Fd), &hc)
+	skipUnsupported(t, err, "SetLineConfig")
+}
+
+// requireInfoWatch skips the test unless the kernel supports watching line
+// info.
+func requireInfoWatch(t *testing.T) {
+	t.Helper()
+	f := openProbeChip(t)
+	defer f.Close()
+	li := uapi.LineInfo{Offset: 0}
+	err := uapi.WatchLineInfo(f.Fd(), &li)
+	skipUnsupported(t, err, "WatchLineInfo")
+	err = uapi.UnwatchLineInfo(f.Fd(), 0)
+	require.Nil(t, err)
+}
+
+// requireUapiV2 skips the test unless the kernel supports the v2 uAPI.
+func requireUapiV2(t *testing.T) {
+	t.Helper()
+	f := openProbeChip(t)
+	defer f.Close()
+	_, err := uapi.GetLineInfoV2(f.Fd(), 0)
+	skipUnsupported(t, err, "uAPI v2")
+}
+
+// openProbeChip opens the first mockup chip to probe the kernel for a
+// feature.
+func openProbeChip(t *testing.T) *os.File {
+	t.Helper()
+	requireMockup(t)
+	c, err := mock.Chip(0)
+	require.Nil(t, err)
+	f, err := os.Open(c.DevPath)
+	require.Nil(t, err)
+	return f
+}
+
+// skipUnsupported skips the test if the error indicates the kernel does not
+// support the ioctl used to probe the feature.
+func skipUnsupported(t *testing.T, err error, feature string) {
+	t.Helper()
+	if err == unix.ENOTTY || err == unix.EINVAL {
+		t.Skipf("kernel does not support %s", feature)
 	}
+	require.Nil(t, err)
 }
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod
+
+import (
+	"context"
+
+	"golang.org/x/sys/unix"
+)
+
+// requestLinesWait requests the lines, waiting for them to be released if
+// they are already in use.
+//
+// The lines are watched for release events rather than being polled.
+func (c *Chip) requestLinesWait(offsets []int, lo LineOptions) (*Lines, error) {
+	ll, err := c.requestLines(offsets, lo)
+	if err != unix.EBUSY {
+		return ll, err
+	}
+	ctx := lo.wait.ctx
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), lo.wait.timeout)
+		defer cancel()
+	}
+	// watch from a separate chip so as not to disturb any watches on c.
+	wc, err := NewChip(c.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer wc.Close()
+	released := make(chan struct{}, 1)
+	for _, o := range offsets {
+		_, err = wc.WatchLineInfo(o, func(evt LineInfoChangeEvent) {
+			if evt.Type != LineReleased {
+				return
+			}
+			select {
+			case released <- struct{}{}:
+			default:
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for {
+		// always retry, as the lines may have been released before the watch
+		// was established, or requested by another process after the release.
+		ll, err = c.requestLines(offsets, lo)
+		if err != unix.EBUSY {
+			return ll, err
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright © 2020 Kent Gibson <warthog618@gmail.com>.
+
+// +build linux
+
+package gpiod_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"golang.org/x/sys/unix"
+)
+
+func TestWithWait(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+
+	c := getChip(t)
+	defer c.Close()
+	offset := platform.FloatingLines()[0]
+
+	// not busy
+	l, err := c.RequestLine(offset, gpiod.WithWait(context.Background()))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+
+	// busy - without wait
+	l2, err := c.RequestLine(offset)
+	assert.Equal(t, unix.EBUSY, err)
+	assert.Nil(t, l2)
+
+	// busy - released while waiting
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l.Close()
+	}()
+	start := time.Now()
+	l2, err = c.RequestLine(offset, gpiod.WithWait(context.Background()))
+	assert.Nil(t, err)
+	require.NotNil(t, l2)
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	// busy - cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	l, err = c.RequestLine(offset, gpiod.WithWait(ctx))
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, l)
+
+	l2.Close()
+}
+
+func TestWithWaitTimeout(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+
+	c := getChip(t)
+	defer c.Close()
+	lines := platform.FloatingLines()
+
+	l, err := c.RequestLine(lines[1])
+	require.Nil(t, err)
+	require.NotNil(t, l)
+
+	// busy - timeout
+	ll, err := c.RequestLines(lines, gpiod.WithWaitTimeout(20*time.Millisecond))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Nil(t, ll)
+
+	// the free line was not left requested
+	inf, err := c.LineInfo(lines[0])
+	assert.Nil(t, err)
+	assert.False(t, inf.Requested)
+
+	// busy - released while waiting
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l.Close()
+	}()
+	ll, err = c.RequestLines(lines, gpiod.AsOutput(1, 0),
+		gpiod.WithWaitTimeout(time.Second))
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+	inf, err = c.LineInfo(lines[1])
+	assert.Nil(t, err)
+	assert.True(t, inf.Requested)
+	assert.True(t, inf.IsOut)
+	ll.Close()
+}
+
+func TestWithWaitEvents(t *testing.T) {
+	requireCapability(t, gpiod.CapInfoWatch)
+
+	c := getChip(t)
+	defer c.Close()
+	lines := append(platform.FloatingLines(), platform.IntrLine())
+
+	l, err := c.RequestLine(platform.IntrLine())
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l.Close()
+	}()
+
+	// the lines requested before the busy line must be released on each
+	// failed attempt, else they would remain busy.
+	ll, err := c.RequestLines(lines,
+		gpiod.WithBothEdges(func(gpiod.LineEvent) {}),
+		gpiod.WithWaitTimeout(time.Second))
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+	ll.Close()
+}
@@ -17,12 +17,23 @@ import (
 type watcher struct {
 	epfd int
 
+	// the name of the chip containing the lines
+	chip string
+
 	// fd to offset mapping
 	evtfds map[int]int
 
 	// the handler for detected events
 	eh EventHandler
 
+	// the edges being watched, if the fds are sysfs value files rather than
+	// event requests.
+	sysfsEdge uapi.EventFlag
+
+	// the poller, if edges are detected by polling, in which case the only
+	// fd is a timerfd.
+	poll *poller
+
 	// pipe to signal watcher to shutdown
 	donefds []int
 
@@ -30,11 +41,20 @@ type watcher struct {
 	doneCh chan struct{}
 }
 
-func newWatcher(fds map[int]int, eh EventHandler) (w *watcher, err error) {
-	var epfd int
-	epfd, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+func newWatcher(chip string, fds map[int]int, eh EventHandler) (*watcher, error) {
+	return startWatcher(&watcher{chip: chip, evtfds: fds, eh: eh})
+}
+
+// newSysfsWatcher creates a watcher for edges on the value files of lines
+// exported via sysfs.
+func newSysfsWatcher(chip string, fds map[int]int, eh EventHandler, edge uapi.EventFlag) (*watcher, error) {
+	return startWatcher(&watcher{chip: chip, evtfds: fds, eh: eh, sysfsEdge: edge})
+}
+
+func startWatcher(w *watcher) (*watcher, error) {
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -44,7 +64,7 @@ func newWatcher(fds map[int]int, eh EventHandler) (w *watcher, err error) {
 	p := []int{0, 0}
 	err = unix.Pipe2(p, unix.O_CLOEXEC)
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -55,24 +75,37 @@ func newWatcher(fds map[int]int, eh EventHandler) (w *watcher, err error) {
 	epv := unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(p[0])}
 	err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, int(p[0]), &epv)
 	if err != nil {
-		return
+		return nil, err
+	}
+	if w.sysfsEdge != 0 {
+		// sysfs signals edges as priority events.
+		epv.Events = unix.EPOLLPRI | unix.EPOLLERR
 	}
-	for fd := range fds {
+	for fd := range w.evtfds {
 		epv.Fd = int32(fd)
 		err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, fd, &epv)
 		if err != nil {
-			return
+			return nil, err
 		}
 	}
-	w = &watcher{
-		epfd:    epfd,
-		evtfds:  fds,
-		eh:      eh,
-		donefds: p,
-		doneCh:  make(chan struct{}),
-	}
+	w.epfd = epfd
+	w.donefds = p
+	w.doneCh = make(chan struct{})
 	go w.watch()
-	return
+	return w, nil
+}
+
+// fdsByOffset returns the event fds for the offsets, in order.
+func (w *watcher) fdsByOffset(offsets []int) []int {
+	fdo := make(map[int]int, len(w.evtfds))
+	for fd, o := range w.evtfds {
+		fdo[o] = fd
+	}
+	fds := make([]int, len(offsets))
+	for i, o := range offsets {
+		fds[i] = fdo[o]
+	}
+	return fds
 }
 
 func (w *watcher) close() {
@@ -81,6 +114,9 @@ func (w *watcher) close() {
 	for fd := range w.evtfds {
 		unix.Close(fd)
 	}
+	if w.poll != nil {
+		w.poll.release()
+	}
 	unix.Close(w.donefds[0])
 	unix.Close(w.donefds[1])
 }
@@ -107,16 +143,37 @@ func (w *watcher) watch() {
 				unix.Close(w.epfd)
 				return
 			}
-			evt, err := uapi.ReadEvent(uintptr(fd))
-			if err != nil {
+			if w.poll != nil {
+				for _, le := range w.poll.sample(w.chip, int(fd)) {
+					w.eh(le)
+				}
 				continue
 			}
-			le := LineEvent{
-				Offset:    w.evtfds[int(fd)],
-				Timestamp: time.Duration(evt.Timestamp),
-				Type:      LineEventType(evt.ID),
+			le, err := w.readEvent(int(fd))
+			if err != nil {
+				continue
 			}
 			w.eh(le)
 		}
 	}
 }
+
+func (w *watcher) readEvent(fd int) (LineEvent, error) {
+	le := LineEvent{
+		Chip:   w.chip,
+		Offset: w.evtfds[fd],
+	}
+	if w.sysfsEdge != 0 {
+		// sysfs does not timestamp events, so the best available is now.
+		var ts unix.Timespec
+		unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts)
+		et, err := readSysfsEvent(fd, w.sysfsEdge)
+		le.Timestamp = time.Duration(ts.Nano())
+		le.Type = et
+		return le, err
+	}
+	evt, err := uapi.ReadEvent(uintptr(fd))
+	le.Timestamp = time.Duration(evt.Timestamp)
+	le.Type = LineEventType(evt.ID)
+	return le, err
+}